@@ -0,0 +1,52 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// adaptedType returns type that unregistered type t can be adapted from if container uses pointer adaptation:
+// T for *T and *T for T
+func (c *Container) adaptedType(t reflect.Type) (reflect.Type, bool) {
+	if !c.adaptPointers || c.hasConstructor(t) {
+		return nil, false
+	}
+
+	if t.Kind() == reflect.Ptr && c.hasConstructor(t.Elem()) {
+		return t.Elem(), true
+	}
+
+	if ptr := reflect.PtrTo(t); c.hasConstructor(ptr) {
+		return ptr, true
+	}
+
+	return nil, false
+}
+
+// adaptValue converts val resolved for adapted type to type t: takes address of a copy of val for pointers
+// and copies value val points to otherwise
+func adaptValue(val reflect.Value, t reflect.Type) (reflect.Value, error) {
+	if t.Kind() == reflect.Ptr && val.Type() == t.Elem() {
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(val)
+		return ptr, nil
+	}
+
+	if val.IsNil() {
+		return reflect.Value{}, fmt.Errorf("can't adapt nil %s to %s", typeName(val.Type()), typeName(t))
+	}
+
+	copied := reflect.New(t).Elem()
+	copied.Set(val.Elem())
+	return copied, nil
+}
+
+// linkAdaptedTypes makes types that are only resolved by adaptation depend on types they are adapted from,
+// so that cyclic dependencies through adaptation are detected
+func (c *Container) linkAdaptedTypes() {
+	for t := range c.constructors {
+		if adapted, ok := c.adaptedType(t); ok {
+			c.graph.addDependency(t, adapted)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package di
+
+import (
+	"reflect"
+	"sync"
+)
+
+type (
+	// Cache stores resolved dependencies by their type, it must be safe for concurrent use
+	Cache interface {
+		// Get returns cached value of type t
+		Get(t reflect.Type) (reflect.Value, bool)
+		// Set caches value of type t
+		Set(t reflect.Type, val reflect.Value)
+		// Delete removes cached value of type t
+		Delete(t reflect.Type)
+	}
+
+	// syncCache is the default Cache, it is safe for concurrent use as dependencies are cached while other goroutines
+	// resolve them through the same container or containers derived from it
+	syncCache struct {
+		m      sync.RWMutex
+		values map[reflect.Type]reflect.Value
+	}
+)
+
+func newSyncCache() Cache {
+	return &syncCache{values: make(map[reflect.Type]reflect.Value)}
+}
+
+func (cache *syncCache) Get(t reflect.Type) (reflect.Value, bool) {
+	cache.m.RLock()
+	defer cache.m.RUnlock()
+
+	val, ok := cache.values[t]
+	return val, ok
+}
+
+func (cache *syncCache) Set(t reflect.Type, val reflect.Value) {
+	cache.m.Lock()
+	defer cache.m.Unlock()
+
+	cache.values[t] = val
+}
+
+func (cache *syncCache) Delete(t reflect.Type) {
+	cache.m.Lock()
+	defer cache.m.Unlock()
+
+	delete(cache.values, t)
+}
+
+// clear removes all cached values, keeping memory allocated for them
+func (cache *syncCache) clear() {
+	cache.m.Lock()
+	defer cache.m.Unlock()
+
+	for t := range cache.values {
+		delete(cache.values, t)
+	}
+}
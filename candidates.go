@@ -0,0 +1,112 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// candidate is a registered type that can be bound to an interface by Build
+type candidate struct {
+	t        reflect.Type
+	priority int
+}
+
+var errNilChooser = errors.New("chooser must not be nil")
+
+// RegisterCandidate registers provider like Register does and makes its out-parameter type a candidate
+// to be bound to the interface pointed to by iface, e.g. (*Storage)(nil). Build binds the interface to one of
+// its candidates: the one returned by the chooser passed to Select or the one with the highest priority.
+// Candidates of equal priority are preferred in order of registration.
+func (c *Container) RegisterCandidate(provider interface{}, iface interface{}, priority int, lifetime Lifetime, opts ...RegisterOption) error {
+	outType, argTypes, constructor, err := newProviderConstructor(provider, nil)
+	if err != nil {
+		return err
+	}
+
+	ifaceType, err := interfaceOf(iface)
+	if err != nil {
+		return err
+	}
+
+	if !outType.Implements(ifaceType) {
+		return fmt.Errorf("type %s does not implement %s", typeName(outType), typeName(ifaceType))
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if err := c.addConstructor(outType, argTypes, constructor, lifetime, opts); err != nil {
+		return err
+	}
+
+	c.candidates[ifaceType] = append(c.candidates[ifaceType], candidate{t: outType, priority: priority})
+	return nil
+}
+
+// Select makes Build bind the interface pointed to by iface to the type returned by chooser.
+// chooser receives types of candidates registered with RegisterCandidate, sorted by priority, and must return one of them,
+// e.g. based on the mode the application was started in.
+func (c *Container) Select(iface interface{}, chooser func(candidates []reflect.Type) reflect.Type) error {
+	ifaceType, err := interfaceOf(iface)
+	if err != nil {
+		return err
+	}
+
+	if chooser == nil {
+		return errNilChooser
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.choosers[ifaceType] = chooser
+	return nil
+}
+
+// bindCandidates binds each interface with candidates to the chosen one, replacing bindings of the previous Build
+func (c *Container) bindCandidates() error {
+	for ifaceType, candidates := range c.candidates {
+		sorted := make([]candidate, len(candidates))
+		copy(sorted, candidates)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].priority > sorted[j].priority
+		})
+
+		types := make([]reflect.Type, len(sorted))
+		for i, cand := range sorted {
+			types[i] = cand.t
+		}
+
+		chosen := types[0]
+		if chooser, ok := c.choosers[ifaceType]; ok {
+			chosen = chooser(types)
+			if !containsType(types, chosen) {
+				return fmt.Errorf("type %s chosen for %s is not its candidate", typeName(chosen), typeName(ifaceType))
+			}
+		}
+
+		if err := c.addConstructor(ifaceType, []reflect.Type{chosen}, func(con *Container) (reflect.Value, error) {
+			return con.resolveArg(chosen)
+		}, c.lifetimes[chosen], nil); err != nil {
+			return err
+		}
+
+		// binding is chosen again by the next Build
+		c.overridable[ifaceType] = true
+	}
+
+	return nil
+}
+
+// containsType checks if types contain t
+func containsType(types []reflect.Type, t reflect.Type) bool {
+	for _, typ := range types {
+		if typ == t {
+			return true
+		}
+	}
+
+	return false
+}
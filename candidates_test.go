@@ -0,0 +1,102 @@
+package di
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterCandidate(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.RegisterCandidate(func() *example {
+		return newExample("low")
+	}, (*exampleInterface)(nil), 1, Singleton)
+	as.NoError(err)
+
+	err = c.RegisterCandidate(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, (*exampleInterface)(nil), 2, Singleton)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(iface exampleInterface, ex2 *example2) {
+		as.Same(ex2, iface)
+	})
+	as.NoError(err)
+}
+
+func TestSelect(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.RegisterCandidate(func() *example {
+		return newExample("example")
+	}, (*exampleInterface)(nil), 1, Transient)
+	as.NoError(err)
+
+	err = c.RegisterCandidate(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, (*exampleInterface)(nil), 2, Singleton)
+	as.NoError(err)
+
+	var candidates []reflect.Type
+	chosen := reflect.TypeOf(&example{})
+	err = c.Select((*exampleInterface)(nil), func(types []reflect.Type) reflect.Type {
+		candidates = types
+		return chosen
+	})
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+	as.Equal([]reflect.Type{reflect.TypeOf(&example2{}), reflect.TypeOf(&example{})}, candidates)
+
+	val, meta, err := c.GetWithMeta(reflect.TypeOf((*exampleInterface)(nil)).Elem())
+	as.NoError(err)
+	as.IsType(&example{}, val)
+	as.Equal(Transient, meta.Lifetime)
+
+	// binding is chosen again by the next Build
+	chosen = reflect.TypeOf(&example2{})
+	err = c.Build()
+	as.NoError(err)
+
+	val, err = c.Get(reflect.TypeOf((*exampleInterface)(nil)).Elem())
+	as.NoError(err)
+	as.IsType(&example2{}, val)
+
+	chosen = reflect.TypeOf(&example3{})
+	err = c.Build()
+	as.EqualError(err, "type *di.example3 chosen for di.exampleInterface is not its candidate")
+
+	err = c.Select((*exampleInterface)(nil), nil)
+	as.Equal(errNilChooser, err)
+}
+
+func TestRegisterCandidateBound(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.RegisterCandidate(func() *example {
+		return newExample("")
+	}, (*exampleInterface)(nil), 1, Singleton)
+	as.NoError(err)
+
+	err = c.RegisterCandidate(func() *example3 {
+		return newExample3()
+	}, (*exampleInterface)(nil), 1, Singleton)
+	as.EqualError(err, "type *di.example3 does not implement di.exampleInterface")
+
+	err = c.Register(func() exampleInterface {
+		return newExample("")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Build()
+	as.EqualError(err, "dependency di.exampleInterface was already registered")
+}
@@ -1,40 +1,113 @@
 package di
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type (
 	// Container is a DI container
 	Container struct {
-		built           bool
-		m               sync.RWMutex
-		scope           scope
-		graph           *dependencyGraph
-		constructors    map[reflect.Type]innerConstructor
-		singletonsCache map[reflect.Type]reflect.Value
-		scopedCache     map[reflect.Type]reflect.Value
-		lifetimes       map[reflect.Type]Lifetime
-		contextParams   ContextParams
+		// m is shared by containers derived from the same root, as they share its registrations and caches,
+		// constructorsM additionally guards constructors that Reload replaces while dependencies are resolved
+		m                *sync.RWMutex
+		constructorsM    *sync.RWMutex
+		built            bool
+		scope            Scope
+		graph            *dependencyGraph
+		constructors     map[reflect.Type]innerConstructor
+		singletonsCache  Cache
+		scopedCache      Cache
+		requestCache     Cache
+		newScopedCache   func() Cache
+		lifetimes        map[reflect.Type]Lifetime
+		contextParams    ContextParams
+		registered       []reflect.Type
+		overridable      map[reflect.Type]bool
+		registrations    map[reflect.Type]*registration
+		named            map[reflect.Type][]string
+		tokens           map[string]reflect.Type
+		groups           map[reflect.Type]int
+		candidates       map[reflect.Type][]candidate
+		choosers         map[reflect.Type]func([]reflect.Type) reflect.Type
+		callCache        map[reflect.Type]reflect.Value
+		sharedTransients bool
+		defaultLifetime  Lifetime
+		allCycles        bool
+		derived          bool
+		middlewares      []Middleware
+		ctx              context.Context
+		nestedScope      bool
+		adaptPointers    bool
+		strictScopes     bool
+		lazySingletons   bool
+		recoverPanics    bool
+		nilCheck         bool
+		consumer         reflect.Type
+		eventHandlers    []func(Event)
+		freezeOnBuild    bool
+		scopedOverrides  *overrideSet
+		fallbacks        []*Container
+		scopePool        *sync.Pool
+		released         *int32
+	}
+
+	// DI is the set of Container's methods used by application code, so that code receiving a container
+	// can depend on DI and be tested with a fake implementation. *Container implements DI. Scopes and contexts
+	// are created with ScopedDI and WithContextDI, which return DI, so that fakes can return fakes as well.
+	DI interface {
+		Register(provider interface{}, lifetime Lifetime, opts ...RegisterOption) error
+		Build() error
+		Invoke(invoker interface{}) error
+		Get(t reflect.Type) (interface{}, error)
+		ScopedDI() DI
+		WithContextDI(key string, value interface{}) DI
 	}
 
 	// Lifetime determines the lifetime of dependencies and whether it can be retrieved from cache or should be
 	// instantiated again based on container's scope
 	Lifetime int
 
+	// ResolveMeta describes how a dependency was resolved
+	ResolveMeta struct {
+		// Lifetime is the registered lifetime of the dependency
+		Lifetime Lifetime
+		// RequestScope is true if the dependency was resolved by a container in request scope
+		RequestScope bool
+		// FromCache is true if the dependency was retrieved from cache instead of being constructed
+		FromCache bool
+	}
+
+	// NotRegisteredError is returned when resolving a dependency that was not registered
+	NotRegisteredError struct {
+		// Type is the type of the dependency
+		Type reflect.Type
+	}
+
 	// ContextParams represents container parameters
 	ContextParams map[string]interface{}
 
-	// innerConstructor calls provider with arguments resolved from the Container
-	innerConstructor func(*Container) reflect.Value
+	// overrideSet holds types overridden with OverrideScoped, it is shared by the request scope container
+	// and containers derived from it
+	overrideSet struct {
+		m     sync.RWMutex
+		types map[reflect.Type]bool
+	}
+
+	// innerConstructor calls provider with arguments resolved from the Container,
+	// errors of resolving the arguments or of initializing the result are returned instead of panicking
+	innerConstructor func(*Container) (reflect.Value, error)
 
-	// scope determines how container resolves dependencies:
-	// container of Request scope will cache Scoped lifetime dependencies
-	scope int
+	// Scope determines how container resolves dependencies:
+	// container of RequestScope will cache Scoped lifetime dependencies
+	Scope int
 )
 
 const (
@@ -44,34 +117,91 @@ const (
 	Scoped Lifetime = 2
 	// Transient lifetime - instatiated once per call
 	Transient Lifetime = 3
+	// LazySingleton lifetime - registers a singleton that is not created by Build, but on its first resolution,
+	// exactly once even if it is resolved concurrently. It is reported as Singleton once registered.
+	LazySingleton Lifetime = 4
 
-	main    scope = 1
-	request scope = 2
+	// MainScope - scope of the container created by NewContainer
+	MainScope Scope = 1
+	// RequestScope - scope of containers created by Scoped
+	RequestScope Scope = 2
+)
+
+var _ DI = (*Container)(nil)
+
+var (
+	// ErrSingletonNotInitialized is returned when a singleton is not found in cache:
+	// it was registered after Build or the cache was changed outside of the container
+	ErrSingletonNotInitialized = errors.New("singleton not initialized")
+	// ErrUnknownLifetime is returned when the lifetime of a registered dependency is unknown
+	ErrUnknownLifetime = errors.New("unknown lifetime")
+	// ErrContainerFrozen is returned when a provider is registered after Build of container created with WithFreezeOnBuild
+	ErrContainerFrozen = errors.New("container is frozen after Build")
+	// ErrResolveTimeout is returned when a provider registered with WithResolveTimeout doesn't return in time
+	ErrResolveTimeout = errors.New("resolve timed out")
 )
 
 var (
 	errNotAFunction       = errors.New("argument is not a function")
+	errNilProvider        = errors.New("provider must not be nil")
+	errNilInvoker         = errors.New("invoker must not be nil")
+	errNilType            = errors.New("type must not be nil")
+	errNilValue           = errors.New("value must not be nil")
 	errOnlyOneOutParam    = errors.New("only one out parameter is allowed")
 	errMustBuildContainer = errors.New("container must be built")
+	errBuildDerived       = errors.New("only the root container can be built, not the one returned by Scoped or WithContext")
+	errInvokerResults     = errors.New("invoker must return nothing or a single error")
+	errSeedNotScoped      = errors.New("values can only be seeded into containers in request scope")
+	errOverrideNotScoped  = errors.New("values can only be overridden in containers in request scope")
+	errReleaseNotScoped   = errors.New("only containers in request scope can be released")
+	errReleased           = errors.New("container was released")
+	errReleaseNested      = errors.New("nested scopes are released with the request scope they were created from")
+	errNotInterface       = errors.New("argument is not a pointer to an interface")
 	contextParamsType     = reflect.TypeOf(ContextParams{})
+	errorType             = reflect.TypeOf((*error)(nil)).Elem()
 )
 
-// NewContainer creates a new container
-func NewContainer() *Container {
-	return &Container{
-		m:               sync.RWMutex{},
+// NewContainer creates a new container configured with opts
+func NewContainer(opts ...Option) *Container {
+	c := &Container{
+		m:               &sync.RWMutex{},
+		constructorsM:   &sync.RWMutex{},
 		built:           false,
 		graph:           newDependencyGraph(),
 		constructors:    make(map[reflect.Type]innerConstructor),
-		singletonsCache: make(map[reflect.Type]reflect.Value),
+		singletonsCache: newSyncCache(),
+		newScopedCache:  newSyncCache,
 		contextParams:   make(map[string]interface{}),
 		lifetimes:       make(map[reflect.Type]Lifetime),
-		scope:           main,
+		registered:      make([]reflect.Type, 0),
+		overridable:     make(map[reflect.Type]bool),
+		registrations:   make(map[reflect.Type]*registration),
+		named:           make(map[reflect.Type][]string),
+		tokens:          make(map[string]reflect.Type),
+		groups:          make(map[reflect.Type]int),
+		candidates:      make(map[reflect.Type][]candidate),
+		choosers:        make(map[reflect.Type]func([]reflect.Type) reflect.Type),
+		defaultLifetime: Transient,
+		scope:           MainScope,
+		ctx:             context.Background(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	return c
+}
+
+// NewContainerWithDefault creates a new container configured with opts,
+// providers registered with Provide get lifetime as their lifetime
+func NewContainerWithDefault(lifetime Lifetime, opts ...Option) *Container {
+	return NewContainer(append([]Option{WithDefaultLifetime(lifetime)}, opts...)...)
 }
 
 // WithContext returns container with added contextParams values without changing the original one.
-// Context allows to change how dependencies are instantiated.
+// Returned container keeps the scope of the original one and shares its caches.
+// Context allows to change how dependencies are instantiated, including transitive ones.
 // Context values can be retrieved in provider functions:
 //  err := c.Register(func(params di.ContextParams) *example {
 //		return newExample(params.GetValue("key").(string))
@@ -83,32 +213,181 @@ func (c *Container) WithContext(key string, value interface{}) *Container {
 	}
 
 	newContext[key] = value
-	newContainer := &Container{
-		m:               sync.RWMutex{},
-		built:           c.built,
-		graph:           c.graph,
-		constructors:    c.constructors,
-		singletonsCache: c.singletonsCache,
-		scopedCache:     c.scopedCache,
-		lifetimes:       c.lifetimes,
-		contextParams:   newContext,
+	newContainer := c.derive()
+	newContainer.contextParams = newContext
+	return newContainer
+}
+
+// WithContextMerge returns container with added contextParams value like WithContext does, but if the key
+// already has a value, merge decides what the new value is based on the old one and value,
+// e.g. to accumulate values across nested contexts.
+func (c *Container) WithContextMerge(key string, value interface{}, merge func(old, new interface{}) interface{}) *Container {
+	if old, ok := c.contextParams[key]; ok && merge != nil {
+		value = merge(old, value)
 	}
 
-	return newContainer
+	return c.WithContext(key, value)
+}
+
+// WithContextDefault sets default value of contextParams key in the container, unlike WithContext it changes
+// the container itself. Containers returned by WithContext after that receive the default unless they set the key,
+// so modules can declare context values that requests may override. Containers derived before the call are not changed.
+// WithContextDefault is meant for setup, like Register: it must not be called while other goroutines derive containers
+// from c or resolve dependencies with it, as they read the context without locking.
+func (c *Container) WithContextDefault(key string, value interface{}) {
+	// the map is shared with derived containers, so it is copied instead of being changed
+	newContext := make(map[string]interface{}, len(c.contextParams)+1)
+	for k, v := range c.contextParams {
+		newContext[k] = v
+	}
+
+	newContext[key] = value
+	c.contextParams = newContext
+}
+
+// Context returns a copy of contextParams of the container, changing it doesn't affect the container
+func (c *Container) Context() ContextParams {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	params := make(ContextParams, len(c.contextParams))
+	for k, v := range c.contextParams {
+		params[k] = v
+	}
+
+	return params
+}
+
+// ContextKeys returns sorted keys of contextParams of the container
+func (c *Container) ContextKeys() []string {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	keys := make([]string, 0, len(c.contextParams))
+	for k := range c.contextParams {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
 }
 
-// Scoped returns new container in request scope
+// ScopedDI returns new container in request scope like Scoped does, as DI
+func (c *Container) ScopedDI() DI {
+	return c.Scoped()
+}
+
+// WithContextDI returns container with added context value like WithContext does, as DI
+func (c *Container) WithContextDI(key string, value interface{}) DI {
+	return c.WithContext(key, value)
+}
+
+// Scoped returns new container in request scope. Calling Scoped on a container in request scope creates a nested
+// scope: its Scoped dependencies are not shared with the parent or sibling scopes, except for ones registered
+// with SharedInNestedScopes, nor are values passed to OverrideScoped. Singletons are shared by all scopes.
 func (c *Container) Scoped() *Container {
+	scoped := c.derive()
+	if c.scopePool != nil {
+		scoped.scopedCache = c.scopePool.Get().(Cache)
+	} else {
+		scoped.scopedCache = c.newScopedCache()
+	}
+
+	scoped.scopedOverrides = &overrideSet{}
+	// the outermost request scope identifies the request, nested scopes share its cache and are released with it
+	if c.scope != RequestScope {
+		scoped.requestCache = scoped.scopedCache
+		scoped.released = new(int32)
+	} else {
+		scoped.nestedScope = true
+	}
+
+	scoped.scope = RequestScope
+	return scoped
+}
+
+// derive returns a copy of container that shares registrations and caches with the original one
+func (c *Container) derive() *Container {
 	return &Container{
-		m:               sync.RWMutex{},
-		built:           c.built,
-		graph:           c.graph,
-		constructors:    c.constructors,
-		singletonsCache: c.singletonsCache,
-		scopedCache:     make(map[reflect.Type]reflect.Value),
-		contextParams:   c.contextParams,
-		lifetimes:       c.lifetimes,
-		scope:           request,
+		m:                c.m,
+		constructorsM:    c.constructorsM,
+		built:            c.built,
+		graph:            c.graph,
+		constructors:     c.constructors,
+		singletonsCache:  c.singletonsCache,
+		scopedCache:      c.scopedCache,
+		requestCache:     c.requestCache,
+		newScopedCache:   c.newScopedCache,
+		lifetimes:        c.lifetimes,
+		contextParams:    c.contextParams,
+		registered:       c.registered,
+		overridable:      c.overridable,
+		registrations:    c.registrations,
+		named:            c.named,
+		tokens:           c.tokens,
+		groups:           c.groups,
+		candidates:       c.candidates,
+		choosers:         c.choosers,
+		callCache:        c.callCache,
+		sharedTransients: c.sharedTransients,
+		defaultLifetime:  c.defaultLifetime,
+		allCycles:        c.allCycles,
+		derived:          true,
+		middlewares:      c.middlewares,
+		ctx:              c.ctx,
+		nestedScope:      c.nestedScope,
+		adaptPointers:    c.adaptPointers,
+		lazySingletons:   c.lazySingletons,
+		recoverPanics:    c.recoverPanics,
+		nilCheck:         c.nilCheck,
+		consumer:         c.consumer,
+		eventHandlers:    c.eventHandlers,
+		freezeOnBuild:    c.freezeOnBuild,
+		scopedOverrides:  c.scopedOverrides,
+		fallbacks:        c.fallbacks,
+		scopePool:        c.scopePool,
+		released:         c.released,
+		strictScopes:     c.strictScopes,
+		scope:            c.scope,
+	}
+}
+
+// forCall returns container that resolves dependencies of a single Invoke or Get call
+func (c *Container) forCall() *Container {
+	if !c.sharedTransients {
+		return c
+	}
+
+	con := c.derive()
+	con.callCache = make(map[reflect.Type]reflect.Value)
+	return con
+}
+
+// String returns the name of lifetime
+func (lifetime Lifetime) String() string {
+	switch lifetime {
+	case Singleton:
+		return "Singleton"
+	case Scoped:
+		return "Scoped"
+	case Transient:
+		return "Transient"
+	case LazySingleton:
+		return "LazySingleton"
+	default:
+		return fmt.Sprintf("Lifetime(%d)", int(lifetime))
+	}
+}
+
+// String returns the name of scope
+func (s Scope) String() string {
+	switch s {
+	case MainScope:
+		return "main"
+	case RequestScope:
+		return "request"
+	default:
+		return fmt.Sprintf("Scope(%d)", int(s))
 	}
 }
 
@@ -121,196 +400,1585 @@ func (contextParams ContextParams) GetValue(key string) interface{} {
 // needs all of its inner parameters to be instantiated.
 // If ContextParams type is passed as an argument, it will give access to container's
 // context parameters.
-func (c *Container) Register(provider interface{}, lifetime Lifetime) error {
-	providerType := reflect.TypeOf(provider)
-	if providerType.Kind() != reflect.Func {
-		return errNotAFunction
-	}
+// Provider that accepts its out-parameter type decorates the dependency registered earlier:
+// it receives the previous instance and returns the one injected into dependents.
+func (c *Container) Register(provider interface{}, lifetime Lifetime, opts ...RegisterOption) error {
+	return c.register(provider, lifetime, nil, opts)
+}
 
-	c.m.Lock()
-	defer c.m.Unlock()
+// Provide registers provider like Register does with container's default lifetime:
+// the one passed to NewContainerWithDefault or Transient
+func (c *Container) Provide(provider interface{}, opts ...RegisterOption) error {
+	return c.register(provider, c.defaultLifetime, nil, opts)
+}
 
-	numOut := providerType.NumOut()
-	if numOut != 1 {
-		return errOnlyOneOutParam
+// RegisterAs registers provider like Register does, but under the interface pointed to by iface, e.g. (*Repository)(nil),
+// instead of provider's out-parameter type, which must implement the interface. Only one provider can be bound
+// to an interface: binding another one fails like any double registration unless the first one was registered
+// with RegisterOverridable.
+func (c *Container) RegisterAs(provider interface{}, iface interface{}, lifetime Lifetime, opts ...RegisterOption) error {
+	outType, argTypes, constructor, err := newProviderConstructor(provider, nil)
+	if err != nil {
+		return err
 	}
 
-	outType := providerType.Out(0)
-	_, ok := c.graph.deps[outType]
-	if ok {
-		return fmt.Errorf("dependency %s was already registered", outType)
+	ifaceType, err := interfaceOf(iface)
+	if err != nil {
+		return err
 	}
 
-	c.graph.addDependency(outType, nil)
+	if !outType.Implements(ifaceType) {
+		return fmt.Errorf("type %s does not implement %s", typeName(outType), typeName(ifaceType))
+	}
 
-	numIn := providerType.NumIn()
-	argTypes := make([]reflect.Type, numIn)
-	for i := 0; i < numIn; i++ {
-		argTypes[i] = providerType.In(i)
+	return c.registerConstructor(ifaceType, argTypes, constructor, lifetime, opts)
+}
+
+// RegisterAsMany registers provider like Register does and binds each of the interfaces pointed to by ifaces,
+// e.g. (*Reader)(nil), to its out-parameter type, which must implement all of them. Interfaces are resolved as
+// the instance of the out-parameter type, so a singleton is the same instance regardless of the type it is requested by.
+// opts only apply to the out-parameter type.
+func (c *Container) RegisterAsMany(provider interface{}, ifaces []interface{}, lifetime Lifetime, opts ...RegisterOption) error {
+	outType, argTypes, constructor, err := newProviderConstructor(provider, nil)
+	if err != nil {
+		return err
 	}
 
-	// out-parameter depends on all of the in-parameters
-	for _, argType := range argTypes {
-		// skip ContextParams
-		if argType == contextParamsType {
-			continue
+	ifaceTypes := make([]reflect.Type, 0, len(ifaces))
+	for _, iface := range ifaces {
+		ifaceType, err := interfaceOf(iface)
+		if err != nil {
+			return err
 		}
 
-		c.graph.addDependency(outType, argType)
-		if _, ok := c.constructors[argType]; !ok {
-			c.constructors[argType] = nil
+		if !outType.Implements(ifaceType) {
+			return fmt.Errorf("type %s does not implement %s", typeName(outType), typeName(ifaceType))
 		}
+
+		ifaceTypes = append(ifaceTypes, ifaceType)
 	}
 
-	providerValue := reflect.ValueOf(provider)
-	innerConstructor := getConstructor(numIn, argTypes, providerValue)
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	// nothing is registered if any of the interfaces was already registered
+	for _, ifaceType := range ifaceTypes {
+		if err := c.checkNotRegistered(ifaceType); err != nil {
+			return err
+		}
+	}
+
+	if err := c.addConstructor(outType, argTypes, constructor, lifetime, opts); err != nil {
+		return err
+	}
+
+	for _, ifaceType := range ifaceTypes {
+		if err := c.addConstructor(ifaceType, []reflect.Type{outType}, func(con *Container) (reflect.Value, error) {
+			return con.resolveArg(outType)
+		}, lifetime, nil); err != nil {
+			return err
+		}
+	}
 
-	c.lifetimes[outType] = lifetime
-	c.constructors[outType] = innerConstructor
 	return nil
 }
 
-func getConstructor(numIn int, argTypes []reflect.Type, providerValue reflect.Value) func(con *Container) reflect.Value {
-	return func(con *Container) reflect.Value {
-		args := make([]reflect.Value, numIn)
-		// resolve each argument and call provider
+// RegisterWithInit registers provider like Register does and calls init on each value constructed by provider
+// before it is cached or returned: for singletons init is called during Build, for Scoped dependencies -
+// once per request scope and for Transient - on each construction. Error returned by init fails the resolution.
+func (c *Container) RegisterWithInit(provider interface{}, lifetime Lifetime, init func(interface{}) error, opts ...RegisterOption) error {
+	return c.register(provider, lifetime, init, opts)
+}
+
+// RegisterBuilder registers build as the provider of out for values that can't be provided by a single function,
+// e.g. ones assembled by builders. Dependencies of types deps are resolved and passed to build in the same order,
+// value returned by build must be assignable to out:
+//  err := c.RegisterBuilder(reflect.TypeOf(&Client{}), []reflect.Type{reflect.TypeOf(&Config{})},
+//		func(args []interface{}) interface{} {
+//			return NewClientBuilder().WithConfig(args[0].(*Config)).Build()
+//		}, di.Singleton)
+func (c *Container) RegisterBuilder(out reflect.Type, deps []reflect.Type, build func(args []interface{}) interface{},
+	lifetime Lifetime, opts ...RegisterOption) error {
+	if out == nil {
+		return errNilType
+	}
+
+	if build == nil {
+		return errNilProvider
+	}
+
+	for _, dep := range deps {
+		if dep == nil {
+			return errNilType
+		}
+	}
+
+	argTypes := append([]reflect.Type(nil), deps...)
+	constructor := func(con *Container) (reflect.Value, error) {
+		args := make([]interface{}, len(argTypes))
 		for i, argType := range argTypes {
-			// get value of ContextParams
-			if argType == contextParamsType {
-				args[i] = reflect.ValueOf(con.contextParams)
-				continue
+			val, err := con.consumedBy(argType, out).resolveProviderArg(argType)
+			if err != nil {
+				return reflect.Value{}, providerArgError(i, argType, out, err)
 			}
 
-			// if arg exists in singletonsCache - retrieve it
-			if val, ok := con.singletonsCache[argType]; ok {
-				args[i] = val
-				continue
-			}
+			args[i] = val.Interface()
+		}
 
-			// if arg exists in scopedCache - retrieve it
-			if val, ok := con.scopedCache[argType]; ok {
-				args[i] = val
-				continue
+		// value is converted to out, so that it is cached as if it was returned by a function providing out
+		result := reflect.New(out).Elem()
+		if built := build(args); built != nil {
+			val := reflect.ValueOf(built)
+			if !val.Type().AssignableTo(out) {
+				return reflect.Value{}, fmt.Errorf("builder of %s returned %s", typeName(out), typeName(val.Type()))
 			}
 
-			// call constructor for argType
-			args[i] = con.constructors[argType](con)
+			result.Set(val)
 		}
 
-		return providerValue.Call(args)[0]
+		return result, nil
 	}
+
+	return c.registerConstructor(out, argTypes, constructor, lifetime, opts)
 }
 
-// Build checks dependency graph for cyclic dependencies, checks if all dependencies
-// were registered and created singletons.
-// Calling Build is required, otherwise Invoke and Get calls will return an error
-func (c *Container) Build() error {
-	err := c.graph.detectCyclicDependencies()
+func (c *Container) register(provider interface{}, lifetime Lifetime, init func(interface{}) error, opts []RegisterOption) error {
+	outType, argTypes, constructor, err := newProviderConstructor(provider, init)
 	if err != nil {
 		return err
 	}
 
-	errs := make([]string, 0)
-	for t, innerConstructor := range c.constructors {
-		// check all innerConstructors, if any of them is nil - no provider was registered for that dependency
-		if innerConstructor == nil {
-			errs = append(errs, fmt.Sprintf("type %s was not registered", t))
+	if containsType(argTypes, outType) {
+		return c.registerDecorator(provider, outType, argTypes, lifetime, init, opts)
+	}
+
+	if init != nil {
+		opts = append(opts[:len(opts):len(opts)], func(reg *registration) {
+			reg.init = init
+		})
+	}
+
+	return c.registerConstructor(outType, argTypes, constructor, lifetime, opts)
+}
+
+// newProviderConstructor validates provider and returns its out-parameter, arguments and constructor that calls it
+func newProviderConstructor(provider interface{}, init func(interface{}) error) (reflect.Type, []reflect.Type, innerConstructor, error) {
+	if isNil(provider) {
+		return nil, nil, nil, errNilProvider
+	}
+
+	providerType := reflect.TypeOf(provider)
+	if providerType.Kind() != reflect.Func {
+		return nil, nil, nil, errNotAFunction
+	}
+
+	numOut := providerType.NumOut()
+	if numOut != 1 {
+		return nil, nil, nil, errOnlyOneOutParam
+	}
+
+	numIn := providerType.NumIn()
+	argTypes := make([]reflect.Type, numIn)
+	for i := 0; i < numIn; i++ {
+		argTypes[i] = providerType.In(i)
+	}
+
+	providerValue := reflect.ValueOf(provider)
+	innerConstructor := getConstructor(numIn, argTypes, providerValue)
+	if init != nil {
+		innerConstructor = withInit(innerConstructor, init)
+	}
+
+	return providerType.Out(0), argTypes, innerConstructor, nil
+}
+
+// registerConstructor adds outType that depends on argTypes to the dependency graph
+// and saves constructor to resolve it with
+func (c *Container) registerConstructor(outType reflect.Type, argTypes []reflect.Type, constructor innerConstructor, lifetime Lifetime, opts []RegisterOption) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.addConstructor(outType, argTypes, constructor, lifetime, opts)
+}
+
+// addConstructor does the same as registerConstructor without locking the container
+func (c *Container) addConstructor(outType reflect.Type, argTypes []reflect.Type, constructor innerConstructor, lifetime Lifetime, opts []RegisterOption) error {
+	if c.frozen() {
+		return ErrContainerFrozen
+	}
+
+	if lifetime != Singleton && lifetime != Scoped && lifetime != Transient && lifetime != LazySingleton {
+		return fmt.Errorf("invalid lifetime %s", lifetime)
+	}
+
+	reg := &registration{}
+	for _, opt := range opts {
+		opt(reg)
+	}
+
+	// lazy singletons only differ from singletons in when they are created
+	if lifetime == LazySingleton {
+		lifetime = Singleton
+		reg.lazy = true
+	}
+
+	if reg.trimmable && lifetime != Singleton {
+		return fmt.Errorf("trimmable dependency %s must be a singleton", typeName(outType))
+	}
+
+	if reg.sharedInNestedScopes && lifetime != Scoped {
+		return fmt.Errorf("dependency %s shared in nested scopes must be scoped", typeName(outType))
+	}
+
+	if reg.noScopeCache && lifetime != Scoped {
+		return fmt.Errorf("dependency %s not cached in scope must be scoped", typeName(outType))
+	}
+
+	if reg.resolveTimeout < 0 {
+		return fmt.Errorf("resolve timeout of %s must not be negative", typeName(outType))
+	}
+
+	// instances created for different consumers can't be shared
+	reg.acceptsRequestInfo = acceptsRequestInfo(argTypes)
+	if reg.acceptsRequestInfo && lifetime != Transient {
+		return fmt.Errorf("dependency %s accepting RequestInfo must be transient", typeName(outType))
+	}
+
+	if err := c.checkNotRegistered(outType); err != nil {
+		return err
+	}
+
+	if err := c.checkToken(reg.token); err != nil {
+		return err
+	}
+
+	// fields of result object are registered as separate dependencies
+	var fields []reflect.StructField
+	if isResultObject(outType) {
+		fields = resultFields(outType)
+		for _, field := range fields {
+			if err := c.checkNotRegistered(field.Type); err != nil {
+				return err
+			}
 		}
+	}
 
-		// if there needs to be a cached value (singleton) - create it
-		if val, ok := c.lifetimes[t]; ok && val == Singleton {
-			c.singletonsCache[t] = c.constructors[t](c)
+	c.replaceOverridable(outType)
+	c.graph.addDependency(outType, nil)
+
+	// out-parameter depends on all of the in-parameters
+	for _, argType := range argTypes {
+		for _, dep := range dependenciesOf(argType) {
+			c.graph.addDependency(outType, dep)
+			if _, ok := c.constructors[dep]; !ok {
+				c.constructors[dep] = nil
+			}
 		}
 	}
 
-	if len(errs) != 0 {
-		return errors.New(strings.Join(errs, "\n"))
+	if reg.resolveTimeout > 0 {
+		constructor = withTimeout(outType, constructor, reg.resolveTimeout)
+	}
+
+	c.lifetimes[outType] = lifetime
+	c.constructors[outType] = constructor
+	c.registrations[outType] = reg
+	c.registered = append(c.registered, outType)
+	if reg.token != "" {
+		c.tokens[reg.token] = outType
 	}
 
-	c.built = true
+	// each field depends on the result object it is taken from
+	for _, field := range fields {
+		c.replaceOverridable(field.Type)
+		c.graph.addDependency(field.Type, outType)
+		c.lifetimes[field.Type] = lifetime
+		c.constructors[field.Type] = getFieldConstructor(outType, field.Index)
+		c.registered = append(c.registered, field.Type)
+	}
+
+	c.emit(Event{Kind: EventRegistered, Type: outType})
 	return nil
 }
 
-// Invoke calls invoker with resolved arguments
-func (c *Container) Invoke(invoker interface{}) error {
-	if !c.built {
-		return errMustBuildContainer
+// frozen checks if container was built with WithFreezeOnBuild, so that providers can't be registered
+func (c *Container) frozen() bool {
+	return c.freezeOnBuild && c.built
+}
+
+// RegisterDefault registers zero value of t as a fallback dependency: it is resolved only until a provider
+// for t is registered with Register, which replaces the default instead of failing as a double registration.
+// Types with a default are considered registered by Build. If t is already registered, RegisterDefault does nothing.
+func (c *Container) RegisterDefault(t reflect.Type) error {
+	if t == nil {
+		return errNilType
 	}
 
-	invokerType := reflect.TypeOf(invoker)
-	if invokerType.Kind() != reflect.Func {
-		return errNotAFunction
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.frozen() {
+		return ErrContainerFrozen
 	}
 
-	numIn := invokerType.NumIn()
-	args := make([]reflect.Value, numIn)
-	for i := 0; i < numIn; i++ {
-		argType := invokerType.In(i)
-		var err error
-		args[i], err = c.getValue(argType)
-		if err != nil {
-			return err
-		}
+	if _, ok := c.graph.deps[t]; ok {
+		return nil
 	}
 
-	// call invoker with resolved arguments
-	reflect.ValueOf(invoker).Call(args)
+	zero := reflect.Zero(t)
+	c.graph.addDependency(t, nil)
+	c.lifetimes[t] = Transient
+	c.constructors[t] = func(*Container) (reflect.Value, error) {
+		return zero, nil
+	}
+	c.overridable[t] = true
 	return nil
 }
 
-// Get returns dependency of type t
-func (c *Container) Get(t reflect.Type) (interface{}, error) {
-	if !c.built {
-		return nil, errMustBuildContainer
+// RegisterOverridable registers provider like Register does, but as a default that can be replaced:
+// a later registration of the same type with Register, Provide or RegisterOverridable replaces it instead of failing
+// as a double registration. It allows libraries to provide defaults that applications override.
+// The latest registration wins, while RegisterDefault does nothing for types that are already registered.
+func (c *Container) RegisterOverridable(provider interface{}, lifetime Lifetime, opts ...RegisterOption) error {
+	outType, argTypes, constructor, err := newProviderConstructor(provider, nil)
+	if err != nil {
+		return err
 	}
 
-	val, err := c.getValue(t)
-	if err != nil {
-		return nil, err
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if err := c.addConstructor(outType, argTypes, constructor, lifetime, opts); err != nil {
+		return err
 	}
 
-	return val.Interface(), nil
+	c.overridable[outType] = true
+	return nil
 }
 
-// getValue resolves dependency
-func (c *Container) getValue(argType reflect.Type) (reflect.Value, error) {
-	// get constructor for type to ensure it was registered
-	constructor, ok := c.constructors[argType]
-	if !ok {
-		return reflect.Value{}, fmt.Errorf("dependency %s was not registered", argType)
+// SetSingleton replaces cached instance of a singleton of value's type with value,
+// subsequent resolutions return the value instead of the instance created by Build.
+// Type of value must be registered with Singleton lifetime.
+func (c *Container) SetSingleton(value interface{}) error {
+	if value == nil {
+		return errNilValue
 	}
 
-	// check lifetime
-	lifetime, ok := c.lifetimes[argType]
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	t := reflect.TypeOf(value)
+	lifetime, ok := c.lifetimes[t]
 	if !ok {
-		return reflect.Value{}, fmt.Errorf("unknown lifetime for dependency %s", argType)
+		return fmt.Errorf("type %s is not registered as singleton", typeName(t))
 	}
 
-	// get value from cache if necessary
-	switch lifetime {
-	case Singleton:
-		// for singletons - always retrieve
-		if cachedValue, ok := c.singletonsCache[argType]; ok {
-			return cachedValue, nil
-		}
+	if lifetime != Singleton {
+		return fmt.Errorf("type %s is registered as %s, not as %s", typeName(t), lifetime, Singleton)
+	}
 
-		return reflect.Value{}, fmt.Errorf("singleton %s not found in cache", argType)
-	case Scoped:
-		// for scoped - retrieve if container is in request scope
-		if c.scope == request {
-			if cachedValue, ok := c.scopedCache[argType]; ok {
-				return cachedValue, nil
-			}
-		}
-		fallthrough
-	default:
-		// for transient or first time scoped invocations - call constructor for type
-		val := constructor(c)
-		// if container scope is request - cache value
-		if c.scope == request {
-			c.scopedCache[argType] = val
-		}
+	c.singletonsCache.Set(t, reflect.ValueOf(value))
+	return nil
+}
 
-		return val, nil
+// SeedScoped caches value as the Scoped instance of value's type in request scope container, so that dependents
+// resolved by the container use it instead of calling the provider. It allows to inject per-request values,
+// e.g. *http.Request. Type of value must be registered with Scoped lifetime.
+func (c *Container) SeedScoped(value interface{}) error {
+	if value == nil {
+		return errNilValue
+	}
+
+	if c.scope != RequestScope {
+		return errSeedNotScoped
+	}
+
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	if c.isReleased() {
+		return errReleased
+	}
+
+	t := reflect.TypeOf(value)
+	lifetime, ok := c.lifetimes[t]
+	if !ok {
+		return fmt.Errorf("type %s is not registered as %s", typeName(t), Scoped)
+	}
+
+	if lifetime != Scoped {
+		return fmt.Errorf("type %s is registered as %s, not as %s", typeName(t), lifetime, Scoped)
+	}
+
+	c.scopedCacheOf(t).Set(t, reflect.ValueOf(value))
+	return nil
+}
+
+// OverrideScoped makes request scope container resolve value as the instance of value's type instead of the registered
+// one, e.g. to substitute a singleton for a single request or test. The value is cached by the container in request
+// scope and by containers derived from it, e.g. with WithContext, even if they were derived before the override:
+// neither the singleton cache nor other scopes, including nested ones, are affected. Dependents that are already
+// cached keep the instance they were created with.
+func (c *Container) OverrideScoped(value interface{}) error {
+	if value == nil {
+		return errNilValue
+	}
+
+	if c.scope != RequestScope {
+		return errOverrideNotScoped
+	}
+
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	if c.isReleased() {
+		return errReleased
+	}
+
+	t := reflect.TypeOf(value)
+	if c.constructors[t] == nil {
+		return fmt.Errorf("type %s is not registered", typeName(t))
+	}
+
+	// value is cached before it is marked as overridden, so that it is found by concurrent resolutions
+	c.scopedCache.Set(t, reflect.ValueOf(value))
+	c.scopedOverrides.m.Lock()
+	defer c.scopedOverrides.m.Unlock()
+
+	if c.scopedOverrides.types == nil {
+		c.scopedOverrides.types = make(map[reflect.Type]bool)
+	}
+
+	c.scopedOverrides.types[t] = true
+	return nil
+}
+
+// scopedOverride returns value of t overridden with OverrideScoped
+func (c *Container) scopedOverride(t reflect.Type) (reflect.Value, bool) {
+	if c.scopedOverrides == nil {
+		return reflect.Value{}, false
+	}
+
+	c.scopedOverrides.m.RLock()
+	overridden := c.scopedOverrides.types[t]
+	c.scopedOverrides.m.RUnlock()
+	if !overridden {
+		return reflect.Value{}, false
+	}
+
+	return c.scopedCache.Get(t)
+}
+
+// Walk calls visit for root and every type it transitively depends on, in depth-first order,
+// along with its depth relative to root. Each type is visited once, even if the graph has cycles.
+// Dependencies of a type are not visited if visit returns false for it.
+func (c *Container) Walk(root reflect.Type, visit func(t reflect.Type, depth int) bool) {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	c.graph.walk(root, 0, make(map[reflect.Type]bool), visit)
+}
+
+// Dependencies returns types that a registered type t directly depends on, in order of provider's arguments.
+// Empty slice is returned for unregistered types.
+func (c *Container) Dependencies(t reflect.Type) []reflect.Type {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	return c.dependencies(t)
+}
+
+// Dependents returns registered types that directly depend on t, sorted by name.
+// Empty slice is returned if there are none.
+func (c *Container) Dependents(t reflect.Type) []reflect.Type {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	dependents := make([]reflect.Type, 0)
+	for from := range c.graph.deps {
+		if c.graph.edges[from][t] {
+			dependents = append(dependents, from)
+		}
+	}
+
+	return sortTypes(dependents)
+}
+
+// PendingTypes returns types that registered providers depend on, but that were not registered yet, sorted by name.
+// These are the types Build would report as not registered. Empty slice is returned if there are none.
+func (c *Container) PendingTypes() []reflect.Type {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	pending := make([]reflect.Type, 0)
+	for t, constructor := range c.constructors {
+		if constructor == nil && !c.provides(t) {
+			pending = append(pending, t)
+		}
+	}
+
+	return sortTypes(pending)
+}
+
+// dependencies returns types that t directly depends on
+func (c *Container) dependencies(t reflect.Type) []reflect.Type {
+	deps := make([]reflect.Type, 0, len(c.graph.deps[t]))
+	for _, dep := range c.graph.deps[t] {
+		if dep != nil {
+			deps = append(deps, dep)
+		}
+	}
+
+	return deps
+}
+
+// ResolvePlan returns types that would be constructed to resolve t by Get, in order of construction,
+// without calling any providers. Dependencies that are cached by the container are not constructed,
+// so neither they nor their dependencies are included. Types that would be cached during resolution are
+// included once, while Transient dependencies are included for each of their dependents, unless the container
+// shares them with WithSharedTransients.
+func (c *Container) ResolvePlan(t reflect.Type) ([]reflect.Type, error) {
+	if t == nil {
+		return nil, errNilType
+	}
+
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	plan := make([]reflect.Type, 0)
+	planned := make(map[reflect.Type]bool)
+	inProgress := make(map[reflect.Type]bool)
+	for _, dep := range dependenciesOf(t) {
+		if err := c.planResolution(dep, planned, inProgress, &plan); err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
+
+// planResolution appends t to plan after its dependencies unless it is cached or was already planned to be cached
+func (c *Container) planResolution(t reflect.Type, planned, inProgress map[reflect.Type]bool, plan *[]reflect.Type) error {
+	if c.constructors[t] == nil {
+		if _, ok := c.adaptedType(t); !ok {
+			return &NotRegisteredError{Type: t}
+		}
+	}
+
+	if planned[t] || c.isCached(t) {
+		return nil
+	}
+
+	// cyclic dependencies are only detected by Build, plan can be requested before it
+	if inProgress[t] {
+		return fmt.Errorf("cyclic dependency detected on %s", typeName(t))
+	}
+
+	inProgress[t] = true
+	for _, dep := range c.dependencies(t) {
+		if err := c.planResolution(dep, planned, inProgress, plan); err != nil {
+			return err
+		}
+	}
+
+	inProgress[t] = false
+
+	// only values that are cached during resolution are constructed once
+	switch c.lifetimes[t] {
+	case Singleton:
+		planned[t] = true
+	case Scoped:
+		planned[t] = c.cachesInScope(t)
+	default:
+		planned[t] = c.sharedTransients
+	}
+
+	*plan = append(*plan, t)
+	return nil
+}
+
+// Resolvable checks that t could be resolved without constructing anything: t and all of its transitive
+// dependencies must be registered and must not depend on each other cyclically. It is a per-type Build check,
+// e.g. for subsystems that are only used behind a feature flag. Returned error describes the first dependency
+// that can't be resolved along with the path to it from t.
+func (c *Container) Resolvable(t reflect.Type) error {
+	if t == nil {
+		return errNilType
+	}
+
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	checked := make(map[reflect.Type]bool)
+	for _, dep := range dependenciesOf(t) {
+		if err := c.checkResolvable(dep, nil, checked); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkResolvable checks that t reached by path and its dependencies are registered and not cyclic
+func (c *Container) checkResolvable(t reflect.Type, path []reflect.Type, checked map[reflect.Type]bool) error {
+	path = append(path[:len(path):len(path)], t)
+	for i, prev := range path[:len(path)-1] {
+		if prev == t {
+			return fmt.Errorf("cyclic dependency detected: %s", formatPath(path[i:]))
+		}
+	}
+
+	if checked[t] {
+		return nil
+	}
+
+	deps := c.dependencies(t)
+	if c.constructors[t] == nil {
+		// fallback container checks the rest of the path with its own registrations
+		if fallback, ok := c.fallbackFor(t); ok {
+			return fallback.checkResolvable(t, path[:len(path)-1], make(map[reflect.Type]bool))
+		}
+
+		adapted, ok := c.adaptedType(t)
+		if !ok {
+			return fmt.Errorf("dependency %s was not registered%s, resolution path: %s",
+				typeName(t), notRegisteredHint(t), formatPath(path))
+		}
+
+		deps = []reflect.Type{adapted}
+	}
+
+	for _, dep := range deps {
+		if err := c.checkResolvable(dep, path, checked); err != nil {
+			return err
+		}
+	}
+
+	checked[t] = true
+	return nil
+}
+
+// formatPath joins names of types of path with arrows
+func formatPath(path []reflect.Type) string {
+	return strings.Join(typeNamesOf(path...), " -> ")
+}
+
+// isCached checks if value of t is currently cached by the container
+func (c *Container) isCached(t reflect.Type) bool {
+	var ok bool
+	switch c.lifetimes[t] {
+	case Singleton:
+		_, ok = c.singletonsCache.Get(t)
+	case Scoped:
+		if c.scope == RequestScope && !c.isReleased() {
+			_, ok = c.scopedCacheOf(t).Get(t)
+		}
+	}
+
+	return ok
+}
+
+// LifetimeOf returns the lifetime t was registered with, ok is false if t was not registered
+func (c *Container) LifetimeOf(t reflect.Type) (lifetime Lifetime, ok bool) {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	if c.constructors[t] == nil {
+		return 0, false
+	}
+
+	lifetime, ok = c.lifetimes[t]
+	return lifetime, ok
+}
+
+// Scope returns the scope of container: MainScope or RequestScope for containers created by Scoped
+func (c *Container) Scope() Scope {
+	return c.scope
+}
+
+// Lifetimes returns a copy of lifetimes of registered dependencies. Named dependencies are not included.
+func (c *Container) Lifetimes() map[reflect.Type]Lifetime {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	lifetimes := make(map[reflect.Type]Lifetime, len(c.lifetimes))
+	for t, lifetime := range c.lifetimes {
+		if _, _, named := parseNamedType(t); named {
+			continue
+		}
+
+		lifetimes[t] = lifetime
+	}
+
+	return lifetimes
+}
+
+// CachedTypes returns types of singletons and Scoped dependencies that are currently cached, sorted by name.
+// Scoped dependencies are only cached by containers in request scope.
+func (c *Container) CachedTypes() (singletons []reflect.Type, scoped []reflect.Type) {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	singletons = make([]reflect.Type, 0)
+	scoped = make([]reflect.Type, 0)
+	for t, lifetime := range c.lifetimes {
+		if !c.isCached(t) {
+			continue
+		}
+
+		if lifetime == Singleton {
+			singletons = append(singletons, t)
+		} else {
+			scoped = append(scoped, t)
+		}
+	}
+
+	return sortTypes(singletons), sortTypes(scoped)
+}
+
+// TrimCache drops trimmable singletons from cache, they are created again on the next resolution.
+// Dropped singletons that implement io.Closer are closed, errors returned by Close are joined into one.
+func (c *Container) TrimCache() error {
+	return c.closeValues(c.dropTrimmable())
+}
+
+// dropTrimmable drops trimmable singletons from cache and returns them to be closed
+func (c *Container) dropTrimmable() []droppedValue {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	var dropped []droppedValue
+	for _, t := range c.registered {
+		if !c.isTrimmable(t) {
+			continue
+		}
+
+		val, ok := c.singletonsCache.Get(t)
+		if !ok {
+			continue
+		}
+
+		c.singletonsCache.Delete(t)
+		dropped = append(dropped, droppedValue{t: t, val: val})
+	}
+
+	return dropped
+}
+
+// isLazy checks if singleton t is created on its first resolution instead of by Build
+func (c *Container) isLazy(t reflect.Type) bool {
+	reg, ok := c.registrations[t]
+	return c.lazySingletons || ok && reg.lazy
+}
+
+// checkTrimmableDependents checks that trimmable singletons are only held by trimmable ones: TrimCache would close
+// a singleton still used by a cached dependent otherwise
+func (c *Container) checkTrimmableDependents() error {
+	for _, t := range c.registered {
+		if _, ok := c.registrations[t]; !ok || c.lifetimes[t] != Singleton || c.isTrimmable(t) {
+			continue
+		}
+
+		for _, dep := range c.heldDependencies(t) {
+			if c.lifetimes[dep] == Singleton && c.isTrimmable(dep) {
+				return fmt.Errorf("singleton %s depends on trimmable %s, so it must be trimmable as well",
+					typeName(t), typeName(dep))
+			}
+		}
+	}
+
+	return nil
+}
+
+// heldDependencies returns dependencies whose instances are held by instances of t: the ones it depends on directly
+// or through non-singletons created for it. Dependencies of other singletons are held by those singletons instead.
+func (c *Container) heldDependencies(t reflect.Type) []reflect.Type {
+	held := make([]reflect.Type, 0)
+	c.graph.walk(t, 0, make(map[reflect.Type]bool), func(dep reflect.Type, depth int) bool {
+		if depth == 0 {
+			return true
+		}
+
+		held = append(held, dep)
+		return c.lifetimes[dep] != Singleton
+	})
+
+	return held
+}
+
+// isTrimmable checks if t was registered as a trimmable singleton
+func (c *Container) isTrimmable(t reflect.Type) bool {
+	reg, ok := c.registrations[t]
+	return ok && reg.trimmable
+}
+
+// scopedCacheOf returns cache of Scoped dependencies of type t: the one of the outermost request scope
+// for dependencies shared in nested scopes or the container's own one
+func (c *Container) scopedCacheOf(t reflect.Type) Cache {
+	if reg, ok := c.registrations[t]; ok && reg.sharedInNestedScopes {
+		return c.requestCache
+	}
+
+	return c.scopedCache
+}
+
+// checkNotRegistered returns an error if a provider that can't be replaced was registered for t
+func (c *Container) checkNotRegistered(t reflect.Type) error {
+	if _, ok := c.graph.deps[t]; ok && !c.overridable[t] {
+		return fmt.Errorf("dependency %s was already registered", typeName(t))
+	}
+
+	return nil
+}
+
+// replaceOverridable removes overridable registration of t, if any, so that it can be registered again
+func (c *Container) replaceOverridable(t reflect.Type) {
+	if !c.overridable[t] {
+		return
+	}
+
+	delete(c.overridable, t)
+	oldDeps := c.graph.deps[t]
+	c.graph.remove(t)
+	// placeholders of unregistered dependencies only the replaced provider had must not fail Build
+	for _, dep := range oldDeps {
+		if constructor, ok := c.constructors[dep]; dep != nil && ok && constructor == nil && !c.graph.hasDependents(dep) {
+			delete(c.constructors, dep)
+		}
+	}
+
+	delete(c.lifetimes, t)
+	delete(c.constructors, t)
+	delete(c.registrations, t)
+	c.singletonsCache.Delete(t)
+	for i, registered := range c.registered {
+		if registered == t {
+			c.registered = append(c.registered[:i:i], c.registered[i+1:]...)
+			break
+		}
+	}
+}
+
+func getConstructor(numIn int, argTypes []reflect.Type, providerValue reflect.Value) innerConstructor {
+	outType := providerValue.Type().Out(0)
+	return func(con *Container) (reflect.Value, error) {
+		args := make([]reflect.Value, numIn)
+		// resolve each argument and call provider
+		for i, argType := range argTypes {
+			var err error
+			args[i], err = con.consumedBy(argType, outType).resolveProviderArg(argType)
+			if err != nil {
+				return reflect.Value{}, providerArgError(i, argType, outType, err)
+			}
+		}
+
+		return providerValue.Call(args)[0], nil
+	}
+}
+
+// providerArgError describes failure to resolve index-th argument of type t of provider of outType
+func providerArgError(index int, t reflect.Type, outType reflect.Type, err error) error {
+	names := typeNamesOf(t, outType)
+	return fmt.Errorf("failed to resolve argument %d (%s) of provider of %s: %w", index, names[0], names[1], err)
+}
+
+// invokerArgError describes failure to resolve index-th argument of type t of invoker
+func invokerArgError(index int, t reflect.Type, err error) error {
+	return fmt.Errorf("failed to resolve argument %d (%s) of invoker: %w", index, typeName(t), err)
+}
+
+// resolveProviderArg resolves provider's argument of any kind: ContextParams, parameter object or a dependency
+func (c *Container) resolveProviderArg(argType reflect.Type) (reflect.Value, error) {
+	// get value of ContextParams or context.Context
+	if val, ok := c.contextArg(argType); ok {
+		return val, nil
+	}
+
+	// assemble parameter object
+	if isParamObject(argType) {
+		return c.newParamObject(argType, c.resolveArg)
+	}
+
+	return c.resolveArg(argType)
+}
+
+// withInit returns constructor that calls init on each value created by constructor
+func withInit(constructor innerConstructor, init func(interface{}) error) innerConstructor {
+	return func(con *Container) (reflect.Value, error) {
+		val, err := constructor(con)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		if err := init(val.Interface()); err != nil {
+			return reflect.Value{}, fmt.Errorf("failed to init %s: %w", typeName(val.Type()), err)
+		}
+
+		return val, nil
+	}
+}
+
+// withTimeout returns constructor that fails with ErrResolveTimeout if constructor doesn't return within timeout.
+// Constructor is called in a separate goroutine that keeps running after the timeout, its result is discarded.
+func withTimeout(t reflect.Type, constructor innerConstructor, timeout time.Duration) innerConstructor {
+	type result struct {
+		val   reflect.Value
+		err   error
+		panic interface{}
+	}
+
+	return func(con *Container) (reflect.Value, error) {
+		// buffered, so that the goroutine doesn't block forever once the result is discarded
+		done := make(chan result, 1)
+		go func() {
+			var res result
+			defer func() {
+				if r := recover(); r != nil {
+					res.panic = r
+				}
+
+				done <- res
+			}()
+
+			res.val, res.err = constructor(con)
+		}()
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case res := <-done:
+			// panics are raised in the resolving goroutine, so that WithPanicRecovery handles them
+			if res.panic != nil {
+				panic(res.panic)
+			}
+
+			return res.val, res.err
+		case <-timer.C:
+			return reflect.Value{}, fmt.Errorf("%w: %s after %s", ErrResolveTimeout, typeName(t), timeout)
+		}
+	}
+}
+
+// constructorOf returns constructor of t, ok is false if t is neither registered nor a dependency of registered types.
+// Constructors are looked up under constructorsM, as Reload may replace them while dependencies are resolved.
+func (c *Container) constructorOf(t reflect.Type) (innerConstructor, bool) {
+	c.constructorsM.RLock()
+	defer c.constructorsM.RUnlock()
+
+	constructor, ok := c.constructors[t]
+	return constructor, ok
+}
+
+// hasConstructor checks if t has a constructor like constructorOf does
+func (c *Container) hasConstructor(t reflect.Type) bool {
+	constructor, _ := c.constructorOf(t)
+	return constructor != nil
+}
+
+// resolveArg resolves provider's argument from caches or by calling its constructor
+func (c *Container) resolveArg(argType reflect.Type) (reflect.Value, error) {
+	if val, ok := c.scopedOverride(argType); ok {
+		return val, nil
+	}
+
+	// if arg exists in singletonsCache - retrieve it
+	if val, ok := c.singletonsCache.Get(argType); ok {
+		return val, nil
+	}
+
+	// if arg exists in scopedCache - retrieve it
+	if c.scope == RequestScope {
+		if val, ok := c.scopedCacheOf(argType).Get(argType); ok {
+			return val, nil
+		}
+	}
+
+	// if arg was already created during this call - retrieve it
+	if val, ok := c.callCache[argType]; ok {
+		return val, nil
+	}
+
+	constructor, _ := c.constructorOf(argType)
+	if constructor == nil {
+		if adapted, ok := c.adaptedType(argType); ok {
+			val, err := c.resolveArg(adapted)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			return adaptValue(val, argType)
+		}
+
+		if fallback, ok := c.fallbackFor(argType); ok {
+			return fallback.getValue(argType)
+		}
+
+		return reflect.Value{}, &NotRegisteredError{Type: argType}
+	}
+
+	// singletons are only created by Build, once it is done they must be found in cache unless they were trimmed
+	if c.built && c.lifetimes[argType] == Singleton && !c.isTrimmable(argType) && !c.isLazy(argType) {
+		return reflect.Value{}, fmt.Errorf("%w: %s; did you call Build?", ErrSingletonNotInitialized, typeName(argType))
+	}
+
+	// lazy singletons are created once even if they are resolved concurrently
+	if reg, ok := c.registrations[argType]; ok && c.lifetimes[argType] == Singleton && c.isLazy(argType) {
+		reg.lazyMu.Lock()
+		defer reg.lazyMu.Unlock()
+
+		// another goroutine may have created the singleton while this one was waiting
+		if val, ok := c.singletonsCache.Get(argType); ok {
+			return val, nil
+		}
+	}
+
+	if err := c.checkScope(argType, c.lifetimes[argType]); err != nil {
+		return reflect.Value{}, err
+	}
+
+	// call constructor for argType
+	val, err := c.construct(argType, constructor)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	// cache value so that other dependents reuse it
+	switch c.lifetimes[argType] {
+	case Singleton:
+		c.singletonsCache.Set(argType, val)
+	case Scoped:
+		if c.cachesInScope(argType) {
+			c.scopedCacheOf(argType).Set(argType, val)
+		}
+	case Transient:
+		if c.callCache != nil && !c.acceptsRequestInfo(argType) {
+			c.callCache[argType] = val
+		}
+	}
+
+	return val, nil
+}
+
+// cachesInScope checks if Scoped dependency of type t is cached by container, i.e. container is in request scope
+// and t was not registered with NoScopeCache
+func (c *Container) cachesInScope(t reflect.Type) bool {
+	if reg, ok := c.registrations[t]; ok && reg.noScopeCache {
+		return false
+	}
+
+	return c.scope == RequestScope
+}
+
+// checkScope forbids resolving Scoped dependencies outside request scope if container uses strict scopes
+func (c *Container) checkScope(t reflect.Type, lifetime Lifetime) error {
+	if c.strictScopes && lifetime == Scoped && c.scope != RequestScope {
+		return fmt.Errorf("scoped type %s resolved outside request scope", typeName(t))
+	}
+
+	return nil
+}
+
+// Build checks dependency graph for cyclic dependencies, checks if all dependencies
+// were registered and created singletons. Dependencies registered with EagerValidate are created once and discarded.
+// Calling Build is required, otherwise Invoke and Get calls will return an error.
+// Build can only be called on the container created by NewContainer: containers derived from it
+// share its singletons and must be created after it was built.
+func (c *Container) Build() error {
+	if c.derived {
+		return errBuildDerived
+	}
+
+	start := time.Now()
+	if err := c.bindCandidates(); err != nil {
+		return err
+	}
+
+	c.linkAdaptedTypes()
+
+	if !c.graph.acyclic {
+		var err error
+		if c.allCycles {
+			err = c.graph.detectAllCyclicDependencies()
+		} else {
+			err = c.graph.detectCyclicDependencies()
+		}
+
+		if err != nil {
+			c.emit(Event{Kind: EventCycleDetected, Err: err})
+			return err
+		}
+
+		c.graph.acyclic = true
+	}
+
+	errs := make([]string, 0)
+	for t, innerConstructor := range c.constructors {
+		// check all innerConstructors, if any of them is nil - no provider was registered for that dependency
+		if innerConstructor == nil && !c.provides(t) {
+			errs = append(errs, fmt.Sprintf("type %s was not registered%s", typeName(t), notRegisteredHint(t)))
+		}
+	}
+
+	if len(errs) != 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+
+	if err := c.checkTrimmableDependents(); err != nil {
+		return err
+	}
+
+	// singletons are created after their dependencies in the same order on each Build
+	types := make([]reflect.Type, 0, len(c.constructors))
+	for t := range c.constructors {
+		types = append(types, t)
+	}
+
+	// singletons registered after the previous Build need to be created as well, while c keeps resolving
+	// dependencies as built if Build fails
+	con := c.derive()
+	con.built = false
+	for _, t := range c.graph.topologicalOrder(types) {
+		// if there needs to be a cached value (singleton) - create it
+		if val, ok := c.lifetimes[t]; ok && val == Singleton && !c.isLazy(t) {
+			// resolveArg caches singleton unless it was already created as a dependency
+			if _, err := con.resolveArg(t); err != nil {
+				return fmt.Errorf("failed to build singleton %s: %w", typeName(t), err)
+			}
+		}
+	}
+
+	if err := c.validateEager(); err != nil {
+		return err
+	}
+
+	if !c.built {
+		c.built = true
+	}
+
+	c.emit(Event{Kind: EventBuilt, Duration: time.Since(start)})
+	return nil
+}
+
+// validateEager constructs and discards an instance of each non-singleton dependency registered with EagerValidate.
+// Instances are created in a separate request scope, so that neither they nor their Scoped dependencies are cached.
+func (c *Container) validateEager() error {
+	var con *Container
+	for _, t := range c.registered {
+		reg, ok := c.registrations[t]
+		if !ok || !reg.eagerValidate || c.lifetimes[t] == Singleton {
+			continue
+		}
+
+		if con == nil {
+			con = c.Scoped()
+		}
+
+		if _, err := con.construct(t, c.constructors[t]); err != nil {
+			return fmt.Errorf("failed to validate %s: %w", typeName(t), err)
+		}
+	}
+
+	return nil
+}
+
+// Invoke calls invoker with resolved arguments. Container must be built even if invoker has no parameters.
+func (c *Container) Invoke(invoker interface{}) error {
+	if err := c.checkInvoker(invoker); err != nil {
+		return err
+	}
+
+	_, err := c.invoke(invoker, nil)
+	return err
+}
+
+// InvokeWith calls invoker like Invoke does, but parameters of types in overrides receive the values from overrides
+// instead of resolved ones, e.g. to pass a stub in tests. Each type of overrides must be a type of invoker's parameter
+// and its value must be assignable to it.
+func (c *Container) InvokeWith(invoker interface{}, overrides map[reflect.Type]interface{}) error {
+	if err := c.checkInvoker(invoker); err != nil {
+		return err
+	}
+
+	invokerType := reflect.TypeOf(invoker)
+	params := make(map[reflect.Type]bool, invokerType.NumIn())
+	for i := 0; i < invokerType.NumIn(); i++ {
+		params[invokerType.In(i)] = true
+	}
+
+	values := make(map[reflect.Type]reflect.Value, len(overrides))
+	for t, value := range overrides {
+		if t == nil {
+			return errNilType
+		}
+
+		if !params[t] {
+			return fmt.Errorf("invoker has no parameter of overridden type %s", typeName(t))
+		}
+
+		val := reflect.Zero(t)
+		if value != nil {
+			val = reflect.ValueOf(value)
+			if !val.Type().AssignableTo(t) {
+				return fmt.Errorf("override of type %s can't be passed as %s", typeName(val.Type()), typeName(t))
+			}
+		}
+
+		values[t] = val
+	}
+
+	_, err := c.invoke(invoker, values)
+	return err
+}
+
+// InvokeE calls invoker with resolved arguments like Invoke does. Invoker must either return nothing or a single error,
+// which is returned by InvokeE if it is not nil.
+func (c *Container) InvokeE(invoker interface{}) error {
+	if err := c.checkInvoker(invoker); err != nil {
+		return err
+	}
+
+	invokerType := reflect.TypeOf(invoker)
+	if invokerType.NumOut() > 1 || invokerType.NumOut() == 1 && invokerType.Out(0) != errorType {
+		return errInvokerResults
+	}
+
+	out, err := c.invoke(invoker, nil)
+	if err != nil {
+		return err
+	}
+
+	if len(out) == 1 && !out[0].IsNil() {
+		return out[0].Interface().(error)
+	}
+
+	return nil
+}
+
+// checkInvoker returns an error if container can't call invoker
+func (c *Container) checkInvoker(invoker interface{}) error {
+	if err := c.checkUsable(); err != nil {
+		return err
+	}
+
+	if isNil(invoker) {
+		return errNilInvoker
+	}
+
+	if reflect.TypeOf(invoker).Kind() != reflect.Func {
+		return errNotAFunction
+	}
+
+	return nil
+}
+
+// invoke calls invoker with resolved arguments and returns its results,
+// arguments of types in overrides are taken from it instead of being resolved
+func (c *Container) invoke(invoker interface{}, overrides map[reflect.Type]reflect.Value) ([]reflect.Value, error) {
+	invokerType := reflect.TypeOf(invoker)
+	con := c.forCall()
+	numIn := invokerType.NumIn()
+	argsPtr := getArgs(numIn)
+	defer putArgs(argsPtr)
+	args := *argsPtr
+	for i := 0; i < numIn; i++ {
+		argType := invokerType.In(i)
+		if val, ok := overrides[argType]; ok {
+			args[i] = val
+			continue
+		}
+
+		// variadic parameter is left empty if neither a group of its elements nor the slice itself was registered
+		if invokerType.IsVariadic() && i == numIn-1 && con.groups[argType.Elem()] == 0 && !con.hasConstructor(argType) {
+			args[i] = reflect.MakeSlice(argType, 0, 0)
+			continue
+		}
+
+		var err error
+		args[i], err = con.getValue(argType)
+		if err != nil {
+			return nil, invokerArgError(i, argType, err)
+		}
+	}
+
+	// call invoker with resolved arguments, variadic parameter receives members of the group spread
+	if invokerType.IsVariadic() {
+		return reflect.ValueOf(invoker).CallSlice(args), nil
+	}
+
+	return reflect.ValueOf(invoker).Call(args), nil
+}
+
+// argsPool holds slices of invokers' arguments, so that calls of Invoke don't allocate them
+var argsPool = sync.Pool{
+	New: func() interface{} {
+		return new([]reflect.Value)
+	},
+}
+
+// getArgs returns pointer to slice of n arguments from argsPool
+func getArgs(n int) *[]reflect.Value {
+	args := argsPool.Get().(*[]reflect.Value)
+	if cap(*args) < n {
+		*args = make([]reflect.Value, n)
+	}
+
+	*args = (*args)[:n]
+	return args
+}
+
+// putArgs returns args to argsPool, clearing them so that the pool doesn't retain resolved values
+func putArgs(args *[]reflect.Value) {
+	for i := range *args {
+		(*args)[i] = reflect.Value{}
+	}
+
+	argsPool.Put(args)
+}
+
+// checkUsable checks that container can resolve dependencies: it was built and was not released
+func (c *Container) checkUsable() error {
+	if !c.built {
+		return errMustBuildContainer
+	}
+
+	if c.isReleased() {
+		return errReleased
+	}
+
+	return nil
+}
+
+// isReleased checks if container in request scope was released with Release, along with the scope it was derived from
+func (c *Container) isReleased() bool {
+	return c.released != nil && atomic.LoadInt32(c.released) == 1
+}
+
+// Get returns dependency of type t
+func (c *Container) Get(t reflect.Type) (interface{}, error) {
+	if err := c.checkUsable(); err != nil {
+		return nil, err
+	}
+
+	if t == nil {
+		return nil, errNilType
+	}
+
+	val, err := c.forCall().getValue(t)
+	if err != nil {
+		return nil, err
+	}
+
+	return val.Interface(), nil
+}
+
+// GetWithMeta returns dependency of type t along with the description of how it was resolved
+func (c *Container) GetWithMeta(t reflect.Type) (interface{}, ResolveMeta, error) {
+	if err := c.checkUsable(); err != nil {
+		return nil, ResolveMeta{}, err
+	}
+
+	if t == nil {
+		return nil, ResolveMeta{}, errNilType
+	}
+
+	val, meta, err := c.forCall().resolve(t)
+	if err != nil {
+		return nil, meta, err
+	}
+
+	return val.Interface(), meta, nil
+}
+
+// ResolveAll returns resolved dependencies of every registered concrete type that implements interface iface.
+// Dependencies are returned in order of registration.
+func (c *Container) ResolveAll(iface reflect.Type) ([]interface{}, error) {
+	if err := c.checkUsable(); err != nil {
+		return nil, err
+	}
+
+	if iface == nil {
+		return nil, errNilType
+	}
+
+	if iface.Kind() != reflect.Interface {
+		return nil, fmt.Errorf("type %s is not an interface", typeName(iface))
+	}
+
+	con := c.forCall()
+	vals := make([]interface{}, 0)
+	for _, t := range c.registered {
+		if t.Kind() == reflect.Interface || !t.Implements(iface) {
+			continue
+		}
+
+		val, err := con.getValue(t)
+		if err != nil {
+			return nil, err
+		}
+
+		vals = append(vals, val.Interface())
+	}
+
+	return vals, nil
+}
+
+// interfaceOf returns interface type pointed to by iface
+func interfaceOf(iface interface{}) (reflect.Type, error) {
+	if iface == nil {
+		return nil, errNilType
+	}
+
+	ifacePtr := reflect.TypeOf(iface)
+	if ifacePtr.Kind() != reflect.Ptr || ifacePtr.Elem().Kind() != reflect.Interface {
+		return nil, errNotInterface
+	}
+
+	return ifacePtr.Elem(), nil
+}
+
+// Error returns the message describing the missing dependency
+func (err *NotRegisteredError) Error() string {
+	return fmt.Sprintf("dependency %s was not registered%s", typeName(err.Type), notRegisteredHint(err.Type))
+}
+
+// notRegisteredHint explains likely reasons why t was not registered
+func notRegisteredHint(t reflect.Type) string {
+	// pointers to interfaces are rarely registered, most likely the interface itself was meant
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Interface {
+		return fmt.Sprintf(": %s is a pointer to interface, use %s instead", typeName(t), typeName(t.Elem()))
+	}
+
+	return ""
+}
+
+// isNil checks if value is nil or a nil func
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+
+	val := reflect.ValueOf(value)
+	return val.Kind() == reflect.Func && val.IsNil()
+}
+
+// getValue resolves dependency
+func (c *Container) getValue(argType reflect.Type) (reflect.Value, error) {
+	val, _, err := c.resolve(argType)
+	return val, err
+}
+
+// resolve resolves dependency and describes whether it was retrieved from cache
+func (c *Container) resolve(argType reflect.Type) (reflect.Value, ResolveMeta, error) {
+	meta := ResolveMeta{RequestScope: c.scope == RequestScope}
+
+	// ContextParams and context.Context are not registered, container's context is used instead
+	if val, ok := c.contextArg(argType); ok {
+		return val, meta, nil
+	}
+
+	// parameter objects are not registered, their fields are resolved instead
+	if isParamObject(argType) {
+		val, err := c.newParamObject(argType, c.getValue)
+		return val, meta, err
+	}
+
+	if val, ok := c.scopedOverride(argType); ok {
+		meta.Lifetime = c.lifetimes[argType]
+		meta.FromCache = true
+		return val, meta, nil
+	}
+
+	// cached singletons are the most frequently resolved dependencies, they don't need a constructor
+	lifetime, hasLifetime := c.lifetimes[argType]
+	if hasLifetime && lifetime == Singleton {
+		if cachedValue, ok := c.singletonsCache.Get(argType); ok {
+			meta.Lifetime = lifetime
+			meta.FromCache = true
+			return cachedValue, meta, nil
+		}
+	}
+
+	// unregistered type can be adapted from a registered pointer or value
+	if adapted, ok := c.adaptedType(argType); ok {
+		val, meta, err := c.resolve(adapted)
+		if err != nil {
+			return reflect.Value{}, meta, err
+		}
+
+		val, err = adaptValue(val, argType)
+		return val, meta, err
+	}
+
+	// get constructor for type to ensure it was registered
+	// placeholders of dependencies of registered types have no constructor either
+	constructor, _ := c.constructorOf(argType)
+	if fallback, found := c.fallbackFor(argType); constructor == nil && found {
+		return fallback.resolve(argType)
+	}
+
+	if constructor == nil {
+		return reflect.Value{}, meta, &NotRegisteredError{Type: argType}
+	}
+
+	// check lifetime
+	if !hasLifetime {
+		return reflect.Value{}, meta, fmt.Errorf("%w for dependency %s", ErrUnknownLifetime, typeName(argType))
+	}
+
+	meta.Lifetime = lifetime
+
+	// get value from cache if necessary
+	switch lifetime {
+	case Singleton:
+		// trimmed and lazy singletons are created on demand
+		if c.isTrimmable(argType) || c.isLazy(argType) {
+			val, err := c.resolveArg(argType)
+			return val, meta, err
+		}
+
+		return reflect.Value{}, meta, fmt.Errorf("%w: %s; did you call Build?", ErrSingletonNotInitialized, typeName(argType))
+	case Scoped:
+		if err := c.checkScope(argType, lifetime); err != nil {
+			return reflect.Value{}, meta, err
+		}
+
+		// for scoped - retrieve if container is in request scope
+		if c.scope == RequestScope {
+			if cachedValue, ok := c.scopedCacheOf(argType).Get(argType); ok {
+				meta.FromCache = true
+				return cachedValue, meta, nil
+			}
+		}
+		// for first time scoped invocations - call constructor for type
+		val, err := c.construct(argType, constructor)
+		if err != nil {
+			return reflect.Value{}, meta, err
+		}
+
+		// if container scope is request - cache value
+		if c.cachesInScope(argType) {
+			c.scopedCacheOf(argType).Set(argType, val)
+		}
+
+		return val, meta, nil
+	default:
+		// for transient - retrieve if it was already created during this call
+		if cachedValue, ok := c.callCache[argType]; ok {
+			meta.FromCache = true
+			return cachedValue, meta, nil
+		}
+
+		// call constructor for type
+		val, err := c.construct(argType, constructor)
+		if err != nil {
+			return reflect.Value{}, meta, err
+		}
+
+		if c.callCache != nil && !c.acceptsRequestInfo(argType) {
+			c.callCache[argType] = val
+		}
+
+		return val, meta, nil
 	}
 }
@@ -1,8 +1,11 @@
 package di
 
 import (
+	"errors"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -380,10 +383,84 @@ func TestNoCachedSingleton(t *testing.T) {
 	as.NoError(err)
 
 	// corrupt container
-	c.singletonsCache = make(map[reflect.Type]reflect.Value)
+	c.singletonsCache = newSyncCache()
 
 	err = c.Invoke(func(ex *example) {})
-	as.Error(err)
+	as.True(errors.Is(err, ErrSingletonNotInitialized))
+}
+
+func TestNoCachedSingletonDependency(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample(time.Now().String())
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	// corrupt container
+	c.singletonsCache = newSyncCache()
+
+	err = c.Invoke(func(ex2 *example2) {})
+	as.EqualError(err, "failed to resolve argument 0 (*di.example2) of invoker: "+
+		"failed to resolve argument 0 (*di.example) of provider of *di.example2: "+
+		"singleton not initialized: *di.example; did you call Build?")
+	as.True(errors.Is(err, ErrSingletonNotInitialized))
+
+	// singleton registered after Build
+	err = c.Register(func() *example3 {
+		return newExample3()
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Invoke(func(ex3 *example3) {})
+	as.EqualError(err, "failed to resolve argument 0 (*di.example3) of invoker: "+
+		"singleton not initialized: *di.example3; did you call Build?")
+	as.True(errors.Is(err, ErrSingletonNotInitialized))
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(ex2 *example2, ex3 *example3) {})
+	as.NoError(err)
+}
+
+func TestFailedRebuild(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("singleton")
+	}, Singleton)
+	as.NoError(err)
+	as.NoError(c.Build())
+
+	err = c.Register(func(ex3 *example3) *example2 {
+		return newExample2(newExample(""))
+	}, Singleton)
+	as.NoError(err)
+	as.EqualError(c.Build(), "type *di.example3 was not registered")
+
+	err = c.RegisterWithInit(newExample3, Transient, func(interface{}) error {
+		return errors.New("init failed")
+	})
+	as.NoError(err)
+	as.EqualError(c.Build(), "failed to build singleton *di.example2: failed to resolve argument 0 (*di.example3) "+
+		"of provider of *di.example2: failed to init *di.example3: init failed")
+
+	// container keeps resolving dependencies it was built with
+	err = c.Invoke(func(ex *example) {
+		as.Equal("singleton", ex.text)
+	})
+	as.NoError(err)
 }
 
 func TestNoLifetime(t *testing.T) {
@@ -402,7 +479,7 @@ func TestNoLifetime(t *testing.T) {
 	c.lifetimes = make(map[reflect.Type]Lifetime)
 
 	err = c.Invoke(func(ex *example) {})
-	as.Error(err)
+	as.True(errors.Is(err, ErrUnknownLifetime))
 }
 
 func TestWithContext(t *testing.T) {
@@ -454,6 +531,133 @@ func TestWithContextInheritContext(t *testing.T) {
 	as.NoError(err)
 }
 
+func TestWithContextSingletonIgnoresContext(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func(params ContextParams) *example {
+		text, _ := ContextValue[string](params, "key")
+		return newExample(text)
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(params ContextParams) *example2 {
+		text, _ := ContextValue[string](params, "key")
+		return newExample2(newExample(text))
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	// singleton was created by Build without context values
+	err = c.WithContext("key", "value").Invoke(func(ex *example) {
+		as.Equal("", ex.text)
+	})
+	as.NoError(err)
+
+	// Scoped dependency of a container in request scope sees context and is created once per scope
+	scoped := c.WithContext("key", "value").Scoped()
+	var first *example2
+	err = scoped.Invoke(func(ex2 *example2) {
+		as.Equal("value", ex2.Text())
+		first = ex2
+	})
+	as.NoError(err)
+
+	err = scoped.Invoke(func(ex2 *example2) {
+		as.Same(first, ex2)
+	})
+	as.NoError(err)
+}
+
+func TestWithContextMerge(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func(params ContextParams) *example {
+		tags, _ := ContextValue[[]string](params, "tags")
+		return newExample(strings.Join(tags, ","))
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	appendTags := func(old, new interface{}) interface{} {
+		return append(append([]string{}, old.([]string)...), new.([]string)...)
+	}
+
+	first := c.WithContextMerge("tags", []string{"first"}, appendTags)
+	second := first.WithContextMerge("tags", []string{"second"}, appendTags)
+	err = second.Invoke(func(ex *example) {
+		as.Equal("first,second", ex.text)
+	})
+	as.NoError(err)
+
+	// original containers are not changed
+	err = first.Invoke(func(ex *example) {
+		as.Equal("first", ex.text)
+	})
+	as.NoError(err)
+
+	err = second.WithContext("tags", []string{"third"}).Invoke(func(ex *example) {
+		as.Equal("third", ex.text)
+	})
+	as.NoError(err)
+}
+
+func TestWithContextDefault(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func(params ContextParams) time.Duration {
+		return params.GetValue("timeout").(time.Duration)
+	}, Transient)
+	as.NoError(err)
+
+	derived := c.WithContext("key", "value")
+	c.WithContextDefault("timeout", time.Second)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(timeout time.Duration) {
+		as.Equal(time.Second, timeout)
+	})
+	as.NoError(err)
+
+	err = c.Scoped().WithContext("key", "value").Invoke(func(timeout time.Duration) {
+		as.Equal(time.Second, timeout)
+	})
+	as.NoError(err)
+
+	err = c.WithContext("timeout", time.Minute).Invoke(func(timeout time.Duration) {
+		as.Equal(time.Minute, timeout)
+	})
+	as.NoError(err)
+
+	// containers derived before the default was set don't receive it
+	as.Nil(derived.contextParams.GetValue("timeout"))
+}
+
+func TestContext(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+	as.Empty(c.ContextKeys())
+	as.Empty(c.Context())
+
+	scoped := c.Scoped().WithContext("user", "admin").WithContext("id", 1)
+	as.Equal([]string{"id", "user"}, scoped.ContextKeys())
+	as.Equal(ContextParams{"id": 1, "user": "admin"}, scoped.Context())
+
+	// returned params are a copy
+	params := scoped.Context()
+	params["user"] = "guest"
+	as.Equal("admin", scoped.contextParams.GetValue("user"))
+	as.Empty(c.ContextKeys())
+}
+
 func TestDoubleRegister(t *testing.T) {
 	as := assert.New(t)
 	c := NewContainer()
@@ -526,6 +730,42 @@ func TestInvokeUnregisteredDependency(t *testing.T) {
 	as.True(strings.HasSuffix(err.Error(), "was not registered"))
 }
 
+func TestNotRegisteredError(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	var notRegistered *NotRegisteredError
+	err = c.Invoke(func(ex *example, ex3 *example3) {})
+	as.True(errors.As(err, &notRegistered))
+	as.Equal(reflect.TypeOf(&example3{}), notRegistered.Type)
+	as.EqualError(notRegistered, "dependency *di.example3 was not registered")
+
+	_, err = c.Get(reflect.TypeOf((*exampleInterface)(nil)))
+	as.True(errors.As(err, &notRegistered))
+	as.Equal(reflect.TypeOf((*exampleInterface)(nil)), notRegistered.Type)
+	as.EqualError(err, "dependency *di.exampleInterface was not registered: "+
+		"*di.exampleInterface is a pointer to interface, use di.exampleInterface instead")
+
+	// dependency of a registered type is not registered itself
+	err = c.Register(func(ex2 *example2) string {
+		return ex2.Text()
+	}, Transient)
+	as.NoError(err)
+
+	_, err = c.Get(reflect.TypeOf(&example2{}))
+	as.True(errors.As(err, &notRegistered))
+	as.Equal(reflect.TypeOf(&example2{}), notRegistered.Type)
+	as.EqualError(err, "dependency *di.example2 was not registered")
+}
+
 func TestRegisterNotFunc(t *testing.T) {
 	as := assert.New(t)
 	c := NewContainer()
@@ -563,6 +803,28 @@ func TestInvokeNotFunc(t *testing.T) {
 	as.Errorf(err, errNotAFunction.Error())
 }
 
+func TestInvokeNoParams(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	called := false
+	invoker := func() {
+		called = true
+	}
+
+	// invoker without parameters still requires the container to be built
+	err := c.Invoke(invoker)
+	as.Equal(errMustBuildContainer, err)
+	as.False(called)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(invoker)
+	as.NoError(err)
+	as.True(called)
+}
+
 func TestNonBuildContainer(t *testing.T) {
 	as := assert.New(t)
 	c := NewContainer()
@@ -602,3 +864,2021 @@ func BenchmarkResolve(b *testing.B) {
 		})
 	}
 }
+
+type node[T any] struct {
+	dep T
+}
+
+type (
+	node1 = *node[*example]
+	node2 = *node[node1]
+	node3 = *node[node2]
+	node4 = *node[node3]
+)
+
+// registerChain registers chain of dependencies of lifetime where each one depends on the previous one
+func registerChain(as *assert.Assertions, c *Container, lifetime Lifetime) {
+	err := c.Register(func() *example {
+		return newExample("")
+	}, lifetime)
+	as.NoError(err)
+
+	err = c.Register(func(dep *example) node1 {
+		return &node[*example]{dep: dep}
+	}, lifetime)
+	as.NoError(err)
+
+	err = c.Register(func(dep node1) node2 {
+		return &node[node1]{dep: dep}
+	}, lifetime)
+	as.NoError(err)
+
+	err = c.Register(func(dep node2) node3 {
+		return &node[node2]{dep: dep}
+	}, lifetime)
+	as.NoError(err)
+
+	err = c.Register(func(dep node3) node4 {
+		return &node[node3]{dep: dep}
+	}, lifetime)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+}
+
+func BenchmarkResolveSingletons(b *testing.B) {
+	as := assert.New(b)
+	b.ReportAllocs()
+
+	c := NewContainer()
+	registerChain(as, c, Singleton)
+
+	for i := 0; i < b.N; i++ {
+		_ = c.Invoke(func(ex *example, n1 node1, n2 node2, n3 node3, n4 node4) {
+		})
+	}
+}
+
+func BenchmarkResolveTransientChain(b *testing.B) {
+	as := assert.New(b)
+	b.ReportAllocs()
+
+	c := NewContainer()
+	registerChain(as, c, Transient)
+
+	for i := 0; i < b.N; i++ {
+		_ = c.Invoke(func(n4 node4) {
+		})
+	}
+}
+
+func BenchmarkResolveScoped(b *testing.B) {
+	as := assert.New(b)
+	b.ReportAllocs()
+
+	c := NewContainer()
+	registerChain(as, c, Scoped)
+
+	for i := 0; i < b.N; i++ {
+		scoped := c.Scoped()
+		_ = scoped.Invoke(func(n4 node4) {
+		})
+		_ = scoped.Invoke(func(ex *example, n1 node1, n2 node2, n3 node3, n4 node4) {
+		})
+	}
+}
+
+func BenchmarkResolveScopedPooled(b *testing.B) {
+	as := assert.New(b)
+	b.ReportAllocs()
+
+	c := NewContainer(WithScopePool())
+	registerChain(as, c, Scoped)
+
+	for i := 0; i < b.N; i++ {
+		scoped := c.Scoped()
+		_ = scoped.Invoke(func(n4 node4) {
+		})
+		_ = scoped.Invoke(func(ex *example, n1 node1, n2 node2, n3 node3, n4 node4) {
+		})
+		_ = scoped.Release()
+	}
+}
+
+func TestNonBuildDerivedContainer(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	scoped := c.Scoped()
+	err = scoped.Invoke(func(ex *example) {})
+	as.EqualError(err, errMustBuildContainer.Error())
+	_, err = scoped.Get(reflect.TypeOf(&example{}))
+	as.EqualError(err, errMustBuildContainer.Error())
+
+	withContext := c.WithContext("key", "value")
+	err = withContext.Invoke(func(ex *example) {})
+	as.EqualError(err, errMustBuildContainer.Error())
+	_, err = withContext.Get(reflect.TypeOf(&example{}))
+	as.EqualError(err, errMustBuildContainer.Error())
+}
+
+func TestBuiltDerivedContainer(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Scoped().Invoke(func(ex *example) {})
+	as.NoError(err)
+
+	err = c.WithContext("key", "value").Invoke(func(ex *example) {})
+	as.NoError(err)
+}
+
+type exampleInterface interface {
+	Text() string
+}
+
+func (ex *example) Text() string {
+	return ex.text
+}
+
+func (ex *example2) Text() string {
+	return ex.Example.text
+}
+
+func TestResolveAll(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func() *example {
+		return newExample("I was injected")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func() *example3 {
+		return newExample3()
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) exampleInterface {
+		return ex
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	vals, err := c.ResolveAll(reflect.TypeOf((*exampleInterface)(nil)).Elem())
+	as.NoError(err)
+	as.Len(vals, 2)
+	as.IsType(&example2{}, vals[0])
+	as.IsType(&example{}, vals[1])
+
+	_, err = c.ResolveAll(reflect.TypeOf(&example{}))
+	as.Error(err)
+}
+
+func TestContextOnlyProvider(t *testing.T) {
+	for _, lifetime := range []Lifetime{Singleton, Scoped, Transient} {
+		as := assert.New(t)
+		c := NewContainer()
+
+		err := c.Register(func(params ContextParams) *example {
+			text, _ := params.GetValue("text").(string)
+			return newExample(text)
+		}, lifetime)
+		as.NoError(err)
+
+		err = c.Build()
+		as.NoError(err)
+
+		// singletons are created on Build, before any context values are set
+		want := "value"
+		if lifetime == Singleton {
+			want = ""
+		}
+
+		err = c.WithContext("text", "value").Invoke(func(ex *example) {
+			as.Equal(want, ex.text)
+		})
+		as.NoError(err)
+
+		scoped := c.WithContext("text", "value").Scoped()
+		first, err := scoped.Get(reflect.TypeOf(&example{}))
+		as.NoError(err)
+		as.Equal(want, first.(*example).text)
+		second, err := scoped.Get(reflect.TypeOf(&example{}))
+		as.NoError(err)
+		as.Equal(lifetime != Transient, first == second)
+
+		scoped = c.Scoped().WithContext("text", "value")
+		first, err = scoped.Get(reflect.TypeOf(&example{}))
+		as.NoError(err)
+		as.Equal(want, first.(*example).text)
+		second, err = scoped.Get(reflect.TypeOf(&example{}))
+		as.NoError(err)
+		as.Equal(lifetime != Transient, first == second)
+	}
+}
+
+func TestNilArguments(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(nil, Transient)
+	as.EqualError(err, errNilProvider.Error())
+
+	var provider func() *example
+	err = c.Register(provider, Transient)
+	as.EqualError(err, errNilProvider.Error())
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(nil)
+	as.EqualError(err, errNilInvoker.Error())
+
+	var invoker func(ex *example)
+	err = c.Invoke(invoker)
+	as.EqualError(err, errNilInvoker.Error())
+
+	_, err = c.Get(nil)
+	as.EqualError(err, errNilType.Error())
+
+	_, err = c.ResolveAll(nil)
+	as.EqualError(err, errNilType.Error())
+}
+
+func TestRegisterDefault(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.RegisterDefault(reflect.TypeOf(&example{}))
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(ex2 *example2) {
+		as.Nil(ex2.Example)
+	})
+	as.NoError(err)
+
+	err = c.Register(func() *example {
+		return newExample("I was injected")
+	}, Singleton)
+	as.NoError(err)
+
+	// default is ignored once the type is registered
+	err = c.RegisterDefault(reflect.TypeOf(&example{}))
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(ex2 *example2) {
+		as.Equal("I was injected", ex2.Example.text)
+	})
+	as.NoError(err)
+
+	err = c.Register(func() *example {
+		return newExample("")
+	}, Transient)
+	as.NotNil(err)
+	as.True(strings.HasSuffix(err.Error(), "was already registered"))
+
+	err = c.RegisterDefault(nil)
+	as.EqualError(err, errNilType.Error())
+}
+
+func TestRegisterOverridable(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.RegisterOverridable(func() *example {
+		return newExample("library")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(ex *example) {
+		as.Equal("library", ex.text)
+	})
+	as.NoError(err)
+
+	err = c.Register(func() *example {
+		return newExample("app")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(ex *example) {
+		as.Equal("app", ex.text)
+	})
+	as.NoError(err)
+
+	// replaced registration is not resolved along with the new one
+	vals, err := c.ResolveAll(reflect.TypeOf((*exampleInterface)(nil)).Elem())
+	as.NoError(err)
+	as.Len(vals, 1)
+
+	err = c.Register(func() *example {
+		return newExample("")
+	}, Transient)
+	as.NotNil(err)
+	as.True(strings.HasSuffix(err.Error(), "was already registered"))
+
+	err = c.RegisterOverridable(func() *example {
+		return newExample("")
+	}, Transient)
+	as.NotNil(err)
+	as.True(strings.HasSuffix(err.Error(), "was already registered"))
+}
+
+func TestRegisterOverridableUnregisteredDependency(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	// dependency of the overridden provider is never registered
+	err := c.RegisterOverridable(func(ex3 *example3) exampleInterface {
+		return newExample("library")
+	}, Singleton)
+	as.NoError(err)
+	as.Equal([]reflect.Type{reflect.TypeOf(&example3{})}, c.PendingTypes())
+
+	err = c.Register(func() exampleInterface {
+		return newExample("app")
+	}, Singleton)
+	as.NoError(err)
+	as.Empty(c.PendingTypes())
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(iface exampleInterface) {
+		as.Equal("app", iface.Text())
+	})
+	as.NoError(err)
+}
+
+func TestGetWithMeta(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Register(func() *example3 {
+		return newExample3()
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	_, meta, err := c.GetWithMeta(reflect.TypeOf(&example{}))
+	as.NoError(err)
+	as.Equal(ResolveMeta{Lifetime: Singleton, RequestScope: false, FromCache: true}, meta)
+
+	_, meta, err = c.GetWithMeta(reflect.TypeOf(&example2{}))
+	as.NoError(err)
+	as.Equal(ResolveMeta{Lifetime: Scoped, RequestScope: false, FromCache: false}, meta)
+
+	c = c.Scoped()
+	_, meta, err = c.GetWithMeta(reflect.TypeOf(&example2{}))
+	as.NoError(err)
+	as.Equal(ResolveMeta{Lifetime: Scoped, RequestScope: true, FromCache: false}, meta)
+	_, meta, err = c.GetWithMeta(reflect.TypeOf(&example2{}))
+	as.NoError(err)
+	as.Equal(ResolveMeta{Lifetime: Scoped, RequestScope: true, FromCache: true}, meta)
+
+	_, meta, err = c.GetWithMeta(reflect.TypeOf(&example3{}))
+	as.NoError(err)
+	as.Equal(ResolveMeta{Lifetime: Transient, RequestScope: true, FromCache: false}, meta)
+	_, meta, err = c.GetWithMeta(reflect.TypeOf(&example3{}))
+	as.NoError(err)
+	as.Equal(ResolveMeta{Lifetime: Transient, RequestScope: true, FromCache: false}, meta)
+
+	_, _, err = c.GetWithMeta(reflect.TypeOf(example{}))
+	as.Error(err)
+}
+
+func TestRegisterBuilder(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("built")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.RegisterBuilder(reflect.TypeOf(&example2{}), []reflect.Type{reflect.TypeOf(&example{})},
+		func(args []interface{}) interface{} {
+			return newExample2(args[0].(*example))
+		}, Scoped)
+	as.NoError(err)
+
+	err = c.RegisterBuilder(reflect.TypeOf((*exampleInterface)(nil)).Elem(), []reflect.Type{reflect.TypeOf(&example2{})},
+		func(args []interface{}) interface{} {
+			return args[0]
+		}, Transient)
+	as.NoError(err)
+
+	err = c.RegisterBuilder(reflect.TypeOf(&example3{}), nil, func(args []interface{}) interface{} {
+		return newExample("wrong type")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	scoped := c.Scoped()
+	err = scoped.Invoke(func(ex2 *example2, iface exampleInterface) {
+		as.Equal("built", ex2.Example.text)
+		as.Same(ex2, iface)
+	})
+	as.NoError(err)
+
+	_, err = c.Get(reflect.TypeOf(&example3{}))
+	as.EqualError(err, "builder of *di.example3 returned *di.example")
+
+	err = c.RegisterBuilder(nil, nil, func(args []interface{}) interface{} {
+		return nil
+	}, Transient)
+	as.Equal(errNilType, err)
+	err = c.RegisterBuilder(reflect.TypeOf(&example3{}), []reflect.Type{nil}, func(args []interface{}) interface{} {
+		return nil
+	}, Transient)
+	as.Equal(errNilType, err)
+	err = c.RegisterBuilder(reflect.TypeOf(&example3{}), nil, nil, Transient)
+	as.Equal(errNilProvider, err)
+}
+
+func TestRegisterWithInit(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	inits := make(map[Lifetime]int)
+	for lifetime, provider := range map[Lifetime]interface{}{
+		Singleton: func() *example { return newExample("") },
+		Scoped:    func() *example2 { return newExample2(nil) },
+		Transient: func() *example3 { return newExample3() },
+	} {
+		lifetime := lifetime
+		err := c.RegisterWithInit(provider, lifetime, func(val interface{}) error {
+			as.NotNil(val)
+			inits[lifetime]++
+			return nil
+		})
+		as.NoError(err)
+	}
+
+	err := c.Build()
+	as.NoError(err)
+	as.Equal(map[Lifetime]int{Singleton: 1}, inits)
+
+	scoped := c.Scoped()
+	for i := 0; i < 2; i++ {
+		err = scoped.Invoke(func(ex *example, ex2 *example2, ex3 *example3) {})
+		as.NoError(err)
+	}
+
+	as.Equal(map[Lifetime]int{Singleton: 1, Scoped: 1, Transient: 2}, inits)
+}
+
+func TestRegisterWithInitError(t *testing.T) {
+	as := assert.New(t)
+	initErr := errors.New("init failed")
+
+	c := NewContainer()
+	err := c.RegisterWithInit(func() *example {
+		return newExample("")
+	}, Singleton, func(interface{}) error {
+		return initErr
+	})
+	as.NoError(err)
+
+	err = c.Build()
+	as.True(errors.Is(err, initErr))
+
+	c = NewContainer()
+	err = c.RegisterWithInit(func() *example {
+		return newExample("")
+	}, Transient, func(interface{}) error {
+		return initErr
+	})
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(ex2 *example2) {})
+	as.True(errors.Is(err, initErr))
+
+	_, err = c.Get(reflect.TypeOf(&example{}))
+	as.True(errors.Is(err, initErr))
+}
+
+func TestSetSingleton(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("original")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	injected := newExample("injected")
+	err = c.SetSingleton(injected)
+	as.NoError(err)
+
+	err = c.Scoped().Invoke(func(ex *example, ex2 *example2) {
+		as.True(injected == ex)
+		as.True(injected == ex2.Example)
+	})
+	as.NoError(err)
+
+	err = c.SetSingleton(newExample2(nil))
+	as.EqualError(err, "type *di.example2 is registered as Transient, not as Singleton")
+
+	err = c.SetSingleton(newExample3())
+	as.EqualError(err, "type *di.example3 is not registered as singleton")
+
+	err = c.SetSingleton(nil)
+	as.EqualError(err, errNilValue.Error())
+}
+
+func TestSeedScoped(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("original")
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	seeded := newExample("seeded")
+	scoped := c.Scoped()
+	err = scoped.SeedScoped(seeded)
+	as.NoError(err)
+
+	err = scoped.Invoke(func(ex *example, ex2 *example2) {
+		as.Same(seeded, ex)
+		as.Same(seeded, ex2.Example)
+	})
+	as.NoError(err)
+
+	// other scopes are not affected
+	err = c.Scoped().Invoke(func(ex *example) {
+		as.Equal("original", ex.text)
+	})
+	as.NoError(err)
+
+	err = c.SeedScoped(seeded)
+	as.Equal(errSeedNotScoped, err)
+
+	err = scoped.SeedScoped(newExample2(nil))
+	as.EqualError(err, "type *di.example2 is registered as Transient, not as Scoped")
+
+	err = scoped.SeedScoped(newExample3())
+	as.EqualError(err, "type *di.example3 is not registered as Scoped")
+
+	err = scoped.SeedScoped(nil)
+	as.Equal(errNilValue, err)
+}
+
+func TestProvideDefaultLifetime(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainerWithDefault(Singleton)
+
+	err := c.Provide(func() *example {
+		return newExample(time.Now().String())
+	})
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	_, meta, err := c.GetWithMeta(reflect.TypeOf(&example{}))
+	as.NoError(err)
+	as.Equal(Singleton, meta.Lifetime)
+
+	_, meta, err = c.GetWithMeta(reflect.TypeOf(&example2{}))
+	as.NoError(err)
+	as.Equal(Transient, meta.Lifetime)
+
+	c = NewContainer()
+	err = c.Provide(func() *example {
+		return newExample(time.Now().String())
+	})
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	_, meta, err = c.GetWithMeta(reflect.TypeOf(&example{}))
+	as.NoError(err)
+	as.Equal(Transient, meta.Lifetime)
+}
+
+func TestWithContextTransitive(t *testing.T) {
+	for _, lifetime := range []Lifetime{Scoped, Transient} {
+		as := assert.New(t)
+		c := NewContainer()
+
+		err := c.Register(func(params ContextParams) *example {
+			return newExample(params.GetValue("text").(string))
+		}, lifetime)
+		as.NoError(err)
+
+		err = c.Register(func(ex *example) *example2 {
+			return newExample2(ex)
+		}, lifetime)
+		as.NoError(err)
+
+		err = c.Register(func(ex2 *example2) *dependsOnExample {
+			return &dependsOnExample{Example: ex2.Example}
+		}, lifetime)
+		as.NoError(err)
+
+		err = c.Build()
+		as.NoError(err)
+
+		for _, con := range []*Container{
+			c.WithContext("text", "value"),
+			c.WithContext("text", "value").Scoped(),
+			c.Scoped().WithContext("text", "value"),
+		} {
+			err = con.Invoke(func(dep *dependsOnExample) {
+				as.Equal("value", dep.Example.text)
+			})
+			as.NoError(err)
+		}
+
+		// request scope keeps values created with the context it had at the time
+		scoped := c.Scoped().WithContext("text", "first")
+		err = scoped.Invoke(func(dep *dependsOnExample) {
+			as.Equal("first", dep.Example.text)
+		})
+		as.NoError(err)
+
+		want := "second"
+		if lifetime == Scoped {
+			want = "first"
+		}
+
+		err = scoped.WithContext("text", "second").Invoke(func(dep *dependsOnExample) {
+			as.Equal(want, dep.Example.text)
+		})
+		as.NoError(err)
+	}
+}
+
+func TestNonStructDependencies(t *testing.T) {
+	type clock func() time.Time
+
+	as := assert.New(t)
+	c := NewContainer()
+	now := time.Now()
+
+	err := c.Register(func() chan string {
+		return make(chan string, 1)
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func() clock {
+		return func() time.Time { return now }
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ch chan string) func(string) {
+		return func(s string) { ch <- s }
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(now clock) map[string]time.Time {
+		return map[string]time.Time{"now": now()}
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Register(func(m map[string]time.Time) []time.Time {
+		return []time.Time{m["now"]}
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func() chan string {
+		return nil
+	}, Singleton)
+	as.NotNil(err)
+	as.True(strings.HasSuffix(err.Error(), "was already registered"))
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(send func(string), ch chan string, now clock, m map[string]time.Time, s []time.Time) {
+		send("sent")
+		as.Equal("sent", <-ch)
+		as.Equal(now(), m["now"])
+		as.Equal([]time.Time{now()}, s)
+	})
+	as.NoError(err)
+
+	val, err := c.Get(reflect.TypeOf(clock(nil)))
+	as.NoError(err)
+	as.Equal(now, val.(clock)())
+}
+
+func TestNonStructCyclicDependency(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func(f func() int) chan int {
+		return make(chan int)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(s []int) func() int {
+		return nil
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(ch chan int) []int {
+		return nil
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NotNil(err)
+	as.True(strings.HasPrefix(err.Error(), "cyclic dependency detected"))
+}
+
+func TestLifetimes(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.RegisterNamed("name", func() *example3 {
+		return newExample3()
+	}, Singleton)
+	as.NoError(err)
+
+	lifetimes := c.Lifetimes()
+	as.Equal(map[reflect.Type]Lifetime{
+		reflect.TypeOf(&example{}):             Singleton,
+		reflect.TypeOf(&example2{}):            Scoped,
+		reflect.TypeOf(map[string]*example3{}): Transient,
+	}, lifetimes)
+
+	// returned map is a copy
+	lifetimes[reflect.TypeOf(&example{})] = Transient
+	as.Equal(Singleton, c.Lifetimes()[reflect.TypeOf(&example{})])
+}
+
+func TestLifetimeOfAndScope(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func(ex3 *example3) *example {
+		return newExample("")
+	}, Scoped)
+	as.NoError(err)
+
+	lifetime, ok := c.LifetimeOf(reflect.TypeOf(&example{}))
+	as.True(ok)
+	as.Equal(Scoped, lifetime)
+
+	// dependencies that were only required are not registered
+	_, ok = c.LifetimeOf(reflect.TypeOf(&example3{}))
+	as.False(ok)
+
+	_, ok = c.LifetimeOf(reflect.TypeOf(&example2{}))
+	as.False(ok)
+
+	as.Equal(MainScope, c.Scope())
+	as.Equal(RequestScope, c.Scoped().Scope())
+	as.Equal(RequestScope, c.Scoped().WithContext("key", "value").Scope())
+}
+
+func TestLifetimeString(t *testing.T) {
+	as := assert.New(t)
+	as.Equal("Singleton", Singleton.String())
+	as.Equal("Scoped", Scoped.String())
+	as.Equal("Transient", Transient.String())
+	as.Equal("LazySingleton", LazySingleton.String())
+	as.Equal("Lifetime(99)", Lifetime(99).String())
+	as.Equal("main", MainScope.String())
+	as.Equal("request", RequestScope.String())
+	as.Equal("Scope(0)", Scope(0).String())
+}
+
+func TestNestedScopes(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("singleton")
+	}, Singleton)
+	as.NoError(err)
+	err = c.Register(newExample2, Scoped)
+	as.NoError(err)
+	as.NoError(c.Build())
+
+	get := func(con *Container) *example2 {
+		val, err := con.Get(reflect.TypeOf(&example2{}))
+		as.NoError(err)
+		return val.(*example2)
+	}
+
+	request := c.Scoped()
+	parent := get(request)
+	first := request.Scoped()
+	second := request.Scoped()
+	as.Equal(RequestScope, first.Scope())
+
+	// scoped instances are cached per scope and not shared with parent or sibling scopes
+	as.Same(get(first), get(first))
+	as.NotSame(parent, get(first))
+	as.NotSame(get(first), get(second))
+	as.NotSame(get(first), get(first.Scoped()))
+
+	// singletons are shared by all scopes
+	as.Same(parent.Example, get(first).Example)
+	as.Same(parent.Example, get(second.Scoped()).Example)
+
+	// overrides belong to a single scope
+	as.NoError(request.OverrideScoped(newExample("override")))
+	val, err := request.Scoped().Get(reflect.TypeOf(&example{}))
+	as.NoError(err)
+	as.Equal("singleton", val.(*example).text)
+}
+
+func TestLazySingleton(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	var calls int32
+	err := c.Register(func() *example {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return newExample("lazy")
+	}, LazySingleton)
+	as.NoError(err)
+	err = c.Register(newExample2, Singleton)
+	as.NoError(err)
+	err = c.Register(newExample3, LazySingleton)
+	as.NoError(err)
+	as.NoError(c.Build())
+
+	// eager singleton creates its lazy dependency, other lazy singletons are not created
+	as.Equal(int32(1), atomic.LoadInt32(&calls))
+	singletons, _ := c.CachedTypes()
+	as.NotContains(singletons, reflect.TypeOf(&example3{}))
+
+	lifetime, ok := c.LifetimeOf(reflect.TypeOf(&example3{}))
+	as.True(ok)
+	as.Equal(Singleton, lifetime)
+
+	ex3, err := c.Get(reflect.TypeOf(&example3{}))
+	as.NoError(err)
+	ex3Again, err := c.Get(reflect.TypeOf(&example3{}))
+	as.NoError(err)
+	as.Same(ex3, ex3Again)
+
+	val, err := c.Get(reflect.TypeOf(&example{}))
+	as.NoError(err)
+	as.Equal("lazy", val.(*example).text)
+	as.Equal(int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestLazySingletonConcurrent(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	var calls int32
+	err := c.Register(func() *example {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return newExample("lazy")
+	}, LazySingleton)
+	as.NoError(err)
+	as.NoError(c.Build())
+	as.Equal(int32(0), atomic.LoadInt32(&calls))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := c.Scoped().Invoke(func(ex *example) {
+				as.Equal("lazy", ex.text)
+			})
+			as.NoError(err)
+		}()
+	}
+
+	wg.Wait()
+	as.Equal(int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRegisterInvalidLifetime(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, 99)
+	as.EqualError(err, "invalid lifetime Lifetime(99)")
+
+	err = NewContainerWithDefault(0).Provide(func() *example {
+		return newExample("")
+	})
+	as.EqualError(err, "invalid lifetime Lifetime(0)")
+
+	// nothing was registered
+	err = c.Build()
+	as.NoError(err)
+
+	_, err = c.Get(reflect.TypeOf(&example{}))
+	as.Error(err)
+}
+
+func TestInvokeWithContextParams(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func(params ContextParams) *example {
+		return newExample(params.GetValue("text").(string))
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	for _, con := range []*Container{c.WithContext("text", "value"), c.Scoped().WithContext("text", "value")} {
+		err = con.Invoke(func(ex *example, params ContextParams) {
+			as.Equal("value", ex.text)
+			as.Equal("value", params.GetValue("text"))
+		})
+		as.NoError(err)
+
+		val, err := con.Get(contextParamsType)
+		as.NoError(err)
+		as.Equal(ContextParams{"text": "value"}, val)
+	}
+}
+
+func TestConstructionErrorPropagation(t *testing.T) {
+	as := assert.New(t)
+	constructErr := errors.New("construction failed")
+	failing := func(interface{}) error {
+		return constructErr
+	}
+
+	c := NewContainer()
+	err := c.RegisterWithInit(func() *example {
+		return newExample("")
+	}, Transient, failing)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Register(func(params taggedParams) *example3 {
+		return newExample3()
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	for _, con := range []*Container{c, c.Scoped()} {
+		err = con.Invoke(func(ex2 *example2) {})
+		as.True(errors.Is(err, constructErr))
+
+		_, err = con.Get(reflect.TypeOf(&example2{}))
+		as.True(errors.Is(err, constructErr))
+
+		_, err = con.Get(reflect.TypeOf(&example3{}))
+		as.True(errors.Is(err, constructErr))
+
+		err = con.Invoke(func(params taggedParams) {})
+		as.True(errors.Is(err, constructErr))
+	}
+
+	// failed scoped dependency is not cached
+	c = c.Scoped()
+	_, meta, err := c.GetWithMeta(reflect.TypeOf(&example2{}))
+	as.True(errors.Is(err, constructErr))
+	as.False(meta.FromCache)
+
+	c = NewContainer()
+	err = c.RegisterWithInit(func() *example {
+		return newExample("")
+	}, Transient, failing)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Build()
+	as.True(errors.Is(err, constructErr))
+}
+
+func TestWalk(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example, params ContextParams) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	depths := make(map[reflect.Type]int)
+	c.Walk(reflect.TypeOf(&example2{}), func(t reflect.Type, depth int) bool {
+		depths[t] = depth
+		return true
+	})
+	as.Equal(map[reflect.Type]int{reflect.TypeOf(&example2{}): 0, reflect.TypeOf(&example{}): 1}, depths)
+}
+
+func TestScopedRequestScopeNested(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	constructed := make(map[string]int)
+	err := c.Register(func() *example {
+		constructed["example"]++
+		return newExample(time.Now().String())
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		constructed["example2"]++
+		return newExample2(ex)
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *dependsOnExample {
+		constructed["dependsOnExample"]++
+		return &dependsOnExample{Example: ex}
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Register(func(ex2 *example2, dep *dependsOnExample) *example3 {
+		constructed["example3"]++
+		as.True(ex2.Example == dep.Example)
+		return newExample3()
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	scoped := c.Scoped()
+	var ex3 *example3
+	err = scoped.Invoke(func(ex3Resolved *example3) {
+		ex3 = ex3Resolved
+	})
+	as.NoError(err)
+	as.Equal(map[string]int{"example": 1, "example2": 1, "dependsOnExample": 1, "example3": 1}, constructed)
+
+	// dependencies created while constructing *example3 were cached
+	for _, t := range []reflect.Type{
+		reflect.TypeOf(&example{}),
+		reflect.TypeOf(&example2{}),
+		reflect.TypeOf(&dependsOnExample{}),
+		reflect.TypeOf(&example3{}),
+	} {
+		_, meta, err := scoped.GetWithMeta(t)
+		as.NoError(err)
+		as.True(meta.FromCache)
+	}
+
+	var ex *example
+	err = scoped.Invoke(func(exResolved *example, ex2 *example2, dep *dependsOnExample, ex3Again *example3) {
+		ex = exResolved
+		as.True(ex == ex2.Example)
+		as.True(ex == dep.Example)
+		as.True(ex3 == ex3Again)
+	})
+	as.NoError(err)
+	as.Equal(map[string]int{"example": 1, "example2": 1, "dependsOnExample": 1, "example3": 1}, constructed)
+
+	// another scope creates its own instances
+	err = c.Scoped().Invoke(func(ex3Other *example3, ex2 *example2) {
+		as.False(ex == ex2.Example)
+	})
+	as.NoError(err)
+	as.Equal(map[string]int{"example": 2, "example2": 2, "dependsOnExample": 2, "example3": 2}, constructed)
+}
+
+func TestBuildDerivedContainer(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	constructed := 0
+	err := c.Register(func() *example {
+		constructed++
+		return newExample("")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Scoped().Build()
+	as.EqualError(err, errBuildDerived.Error())
+
+	err = c.WithContext("key", "value").Build()
+	as.EqualError(err, errBuildDerived.Error())
+	as.Equal(0, constructed)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Scoped().WithContext("key", "value").Build()
+	as.EqualError(err, errBuildDerived.Error())
+	as.Equal(1, constructed)
+}
+
+func TestDependencies(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example, params ContextParams, ex3 *example3) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	as.Equal([]reflect.Type{reflect.TypeOf(&example{}), reflect.TypeOf(&example3{})}, c.Dependencies(reflect.TypeOf(&example2{})))
+	as.Empty(c.Dependencies(reflect.TypeOf(&example{})))
+	as.Empty(c.Dependencies(reflect.TypeOf(&example3{})))
+}
+
+func TestDependents(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(params exampleParams) *dependsOnExample {
+		return &dependsOnExample{Example: params.Example}
+	}, Transient)
+	as.NoError(err)
+
+	as.Equal([]reflect.Type{reflect.TypeOf(&dependsOnExample{}), reflect.TypeOf(&example2{})}, c.Dependents(reflect.TypeOf(&example{})))
+	as.Equal([]reflect.Type{reflect.TypeOf(&dependsOnExample{})}, c.Dependents(reflect.TypeOf(&example3{})))
+	as.Empty(c.Dependents(reflect.TypeOf(&example2{})))
+}
+
+func TestInvokeE(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	errRun := errors.New("run failed")
+	err = c.InvokeE(func(ex *example) error {
+		return errRun
+	})
+	as.Equal(errRun, err)
+
+	err = c.InvokeE(func(ex *example) error {
+		return nil
+	})
+	as.NoError(err)
+
+	called := false
+	err = c.InvokeE(func(ex *example) {
+		called = true
+	})
+	as.NoError(err)
+	as.True(called)
+
+	err = c.InvokeE(func(ex *example) *example {
+		return ex
+	})
+	as.Equal(errInvokerResults, err)
+
+	err = c.InvokeE(func(ex *example) (int, error) {
+		return 0, nil
+	})
+	as.Equal(errInvokerResults, err)
+
+	err = c.InvokeE(func(ex3 *example3) error {
+		return nil
+	})
+	as.True(strings.HasSuffix(err.Error(), "was not registered"))
+
+	err = c.InvokeE(nil)
+	as.Equal(errNilInvoker, err)
+}
+
+func TestPointerToInterface(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() exampleInterface {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	_, err = c.Get(reflect.TypeOf((*exampleInterface)(nil)))
+	as.EqualError(err, "dependency *di.exampleInterface was not registered: "+
+		"*di.exampleInterface is a pointer to interface, use di.exampleInterface instead")
+
+	err = c.Register(func(iface *exampleInterface) *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.EqualError(err, "type *di.exampleInterface was not registered: "+
+		"*di.exampleInterface is a pointer to interface, use di.exampleInterface instead")
+}
+
+func TestCachedTypes(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Register(func() *example3 {
+		return newExample3()
+	}, Transient)
+	as.NoError(err)
+
+	singletons, scoped := c.CachedTypes()
+	as.Empty(singletons)
+	as.Empty(scoped)
+
+	err = c.Build()
+	as.NoError(err)
+
+	_, err = c.Get(reflect.TypeOf(&example2{}))
+	as.NoError(err)
+
+	singletons, scoped = c.CachedTypes()
+	as.Equal([]reflect.Type{reflect.TypeOf(&example{})}, singletons)
+	as.Empty(scoped)
+
+	request := c.Scoped()
+	err = request.Invoke(func(ex2 *example2, ex3 *example3) {})
+	as.NoError(err)
+
+	singletons, scoped = request.CachedTypes()
+	as.Equal([]reflect.Type{reflect.TypeOf(&example{})}, singletons)
+	as.Equal([]reflect.Type{reflect.TypeOf(&example2{})}, scoped)
+}
+
+func TestRegisterAs(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.RegisterAs(func() *example {
+		return newExample("bound")
+	}, (*exampleInterface)(nil), Singleton)
+	as.NoError(err)
+
+	err = c.RegisterAs(func() *example2 {
+		return newExample2(nil)
+	}, (*exampleInterface)(nil), Singleton)
+	as.EqualError(err, "dependency di.exampleInterface was already registered")
+
+	err = c.RegisterAs(func() *example3 {
+		return newExample3()
+	}, (*exampleInterface)(nil), Singleton)
+	as.EqualError(err, "type *di.example3 does not implement di.exampleInterface")
+
+	err = c.RegisterAs(func() *example3 {
+		return newExample3()
+	}, &example3{}, Singleton)
+	as.Equal(errNotInterface, err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(iface exampleInterface) {
+		as.Equal("bound", iface.Text())
+	})
+	as.NoError(err)
+
+	// concrete type is not registered
+	_, err = c.Get(reflect.TypeOf(&example{}))
+	as.True(strings.HasSuffix(err.Error(), "was not registered"))
+}
+
+func TestRegisterAsOverridable(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.RegisterOverridable(func() exampleInterface {
+		return newExample("default")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.RegisterAs(func() *example {
+		return newExample("bound")
+	}, (*exampleInterface)(nil), Singleton)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(iface exampleInterface) {
+		as.Equal("bound", iface.Text())
+	})
+	as.NoError(err)
+}
+
+func TestArgumentErrors(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.RegisterWithInit(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient, func(interface{}) error {
+		return errors.New("init failed")
+	})
+	as.NoError(err)
+
+	err = c.Register(func(ex *example, ex2 *example2) *example3 {
+		return newExample3()
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(ex *example, params ContextParams, ex3 *example3) {})
+	as.EqualError(err, "failed to resolve argument 2 (*di.example3) of invoker: "+
+		"failed to resolve argument 1 (*di.example2) of provider of *di.example3: "+
+		"failed to init *di.example2: init failed")
+
+	err = Invoke2(c, func(ex *example, ex2 *example2) {})
+	as.EqualError(err, "failed to resolve argument 1 (*di.example2) of invoker: failed to init *di.example2: init failed")
+}
+
+type otherInterface interface {
+	Other()
+}
+
+func (ex *example) Other() {}
+
+func TestRegisterAsMany(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	calls := 0
+	err := c.RegisterAsMany(func() *example {
+		calls++
+		return newExample("shared")
+	}, []interface{}{(*exampleInterface)(nil), (*otherInterface)(nil)}, Singleton)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+	as.Equal(1, calls)
+
+	err = c.Invoke(func(ex *example, iface exampleInterface, other otherInterface) {
+		as.Same(ex, iface)
+		as.Same(ex, other)
+	})
+	as.NoError(err)
+
+	err = c.Scoped().Invoke(func(ex *example, iface exampleInterface, other otherInterface) {
+		as.Same(ex, iface)
+		as.Same(ex, other)
+	})
+	as.NoError(err)
+	as.Equal(1, calls)
+}
+
+func TestRegisterAsManyErrors(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.RegisterAsMany(func() *example2 {
+		return newExample2(nil)
+	}, []interface{}{(*exampleInterface)(nil), (*otherInterface)(nil)}, Singleton)
+	as.EqualError(err, "type *di.example2 does not implement di.otherInterface")
+
+	err = c.RegisterAs(func() *example2 {
+		return newExample2(nil)
+	}, (*exampleInterface)(nil), Singleton)
+	as.NoError(err)
+
+	err = c.RegisterAsMany(func() *example {
+		return newExample("")
+	}, []interface{}{(*otherInterface)(nil), (*exampleInterface)(nil)}, Singleton)
+	as.EqualError(err, "dependency di.exampleInterface was already registered")
+
+	// failed registration leaves nothing registered
+	as.Equal(map[reflect.Type]Lifetime{
+		reflect.TypeOf((*exampleInterface)(nil)).Elem(): Singleton,
+	}, c.Lifetimes())
+}
+
+func TestResolvePlan(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	calls := 0
+	err := c.Register(func() *example {
+		calls++
+		return newExample("")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		calls++
+		return newExample2(ex)
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Register(func() *example3 {
+		calls++
+		return newExample3()
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(ex2 *example2, ex3 *example3, params exampleParams) *dependsOnExample {
+		calls++
+		return &dependsOnExample{}
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(ex3 *example3, dep *dependsOnExample) *example3Holder {
+		return &example3Holder{}
+	}, Transient)
+	as.NoError(err)
+
+	// transient dependency is constructed for each of its dependents
+	plan, err := c.ResolvePlan(reflect.TypeOf(&example3Holder{}))
+	as.NoError(err)
+	as.Equal([]reflect.Type{
+		reflect.TypeOf(&example3{}),
+		reflect.TypeOf(&example{}),
+		reflect.TypeOf(&example2{}),
+		reflect.TypeOf(&example3{}),
+		reflect.TypeOf(&dependsOnExample{}),
+		reflect.TypeOf(&example3Holder{}),
+	}, plan)
+
+	// singletons are not created before Build
+	plan, err = c.ResolvePlan(reflect.TypeOf(&dependsOnExample{}))
+	as.NoError(err)
+	as.Equal([]reflect.Type{
+		reflect.TypeOf(&example{}),
+		reflect.TypeOf(&example2{}),
+		reflect.TypeOf(&example3{}),
+		reflect.TypeOf(&dependsOnExample{}),
+	}, plan)
+
+	err = c.Build()
+	as.NoError(err)
+	calls = 0
+
+	plan, err = c.ResolvePlan(reflect.TypeOf(&dependsOnExample{}))
+	as.NoError(err)
+	as.Equal([]reflect.Type{
+		reflect.TypeOf(&example2{}),
+		reflect.TypeOf(&example3{}),
+		reflect.TypeOf(&dependsOnExample{}),
+	}, plan)
+
+	scoped := c.Scoped()
+	_, err = scoped.Get(reflect.TypeOf(&example2{}))
+	as.NoError(err)
+	calls = 0
+
+	plan, err = scoped.ResolvePlan(reflect.TypeOf(exampleParams{}))
+	as.NoError(err)
+	as.Equal([]reflect.Type{reflect.TypeOf(&example3{})}, plan)
+	as.Equal(0, calls)
+
+	_, err = c.ResolvePlan(reflect.TypeOf(&closer{}))
+	as.EqualError(err, "dependency *di.closer was not registered")
+}
+
+type example3Holder struct{}
+
+func TestResolvePlanCycle(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func(ex2 *example2) *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	_, err = c.ResolvePlan(reflect.TypeOf(&example{}))
+	as.EqualError(err, "cyclic dependency detected on *di.example")
+}
+
+func TestResolvable(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func(ex3 *example3) *example {
+		return newExample("")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(params ContextParams) *config {
+		return &config{}
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Resolvable(reflect.TypeOf(&example2{}))
+	as.EqualError(err, "dependency *di.example3 was not registered, resolution path: *di.example2 -> *di.example -> *di.example3")
+
+	err = c.Resolvable(reflect.TypeOf(&config{}))
+	as.NoError(err)
+
+	err = c.Register(func(ex2 *example2) *example3 {
+		return newExample3()
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Resolvable(reflect.TypeOf(&example2{}))
+	as.EqualError(err, "cyclic dependency detected: *di.example2 -> *di.example -> *di.example3 -> *di.example2")
+
+	err = c.Resolvable(nil)
+	as.Equal(errNilType, err)
+}
+
+func TestInvokeConcurrentArgs(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+	registerChain(as, c, Singleton)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				// arguments of calls with different signatures share pooled slices
+				err := c.Invoke(func(ex *example, n1 node1, n2 node2) {
+					as.Same(ex, n1.dep)
+					as.Same(n1, n2.dep)
+				})
+				as.NoError(err)
+
+				err = c.Invoke(func(n3 node3) {
+					as.NotNil(n3)
+				})
+				as.NoError(err)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestWithContextConcurrent(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("lazy")
+	}, LazySingleton)
+	as.NoError(err)
+	err = c.Register(newExample2, Singleton, Trimmable())
+	as.NoError(err)
+	err = c.Register(func() *example3 {
+		return newExample3()
+	}, Scoped)
+	as.NoError(err)
+	as.NoError(c.Build())
+	as.NoError(c.TrimCache())
+
+	scoped := c.Scoped()
+	derived := []*Container{c, c.WithContext("key", "value"), scoped, scoped.WithContext("key", "value")}
+	for _, con := range derived {
+		as.Same(c.m, con.m)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		con := derived[i%len(derived)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				err := con.Invoke(func(ex *example, ex2 *example2, ex3 *example3) {
+					as.Equal("lazy", ex.text)
+				})
+				as.NoError(err)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestBuildSingletonsOrder(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	order := make([]string, 0)
+	err := c.Register(func(ex *example, ex3 *example3) *example2 {
+		order = append(order, "example2")
+		return newExample2(ex)
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ex3 *example3) *example {
+		order = append(order, "example")
+		return newExample("")
+	}, Singleton)
+	as.NoError(err)
+
+	// shared dependency is constructed once
+	err = c.Register(func() *example3 {
+		order = append(order, "example3")
+		return newExample3()
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+	as.Equal([]string{"example3", "example", "example2"}, order)
+}
+
+// fakeDI resolves dependencies from a map instead of providers
+type fakeDI struct {
+	DI
+	values map[reflect.Type]interface{}
+	scope  *fakeDI
+}
+
+func (f *fakeDI) Get(t reflect.Type) (interface{}, error) {
+	return f.values[t], nil
+}
+
+func (f *fakeDI) ScopedDI() DI {
+	return f.scope
+}
+
+func (f *fakeDI) WithContextDI(string, interface{}) DI {
+	return f
+}
+
+func TestDIFake(t *testing.T) {
+	as := assert.New(t)
+
+	textOf := func(c DI) string {
+		ex, err := c.Get(reflect.TypeOf(&example{}))
+		as.NoError(err)
+		return ex.(*example).text
+	}
+
+	c := NewContainer()
+	err := c.Register(func() *example {
+		return newExample("real")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	as.Equal("real", textOf(c))
+	as.Equal("fake", textOf(&fakeDI{values: map[reflect.Type]interface{}{
+		reflect.TypeOf(&example{}): newExample("fake"),
+	}}))
+
+	// code resolving dependencies in a request scope gets the fake's scope
+	scopedTextOf := func(c DI) string {
+		return textOf(c.ScopedDI().WithContextDI("key", "value"))
+	}
+
+	as.Equal("real", scopedTextOf(c))
+	as.Equal("fake scoped", scopedTextOf(&fakeDI{scope: &fakeDI{values: map[reflect.Type]interface{}{
+		reflect.TypeOf(&example{}): newExample("fake scoped"),
+	}}}))
+}
+
+func TestPendingTypes(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+	as.Empty(c.PendingTypes())
+
+	err := c.Register(func(ex *example, ex3 *example3) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+	as.Equal([]reflect.Type{reflect.TypeOf(&example{}), reflect.TypeOf(&example3{})}, c.PendingTypes())
+
+	err = c.Register(func(ex3 *example3) *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+	as.Equal([]reflect.Type{reflect.TypeOf(&example3{})}, c.PendingTypes())
+
+	err = c.Register(func() *example3 {
+		return newExample3()
+	}, Transient)
+	as.NoError(err)
+	as.Empty(c.PendingTypes())
+}
+
+func TestInterfaceOutType(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("text")
+	}, Singleton)
+	as.NoError(err)
+
+	// out-parameter of interface type is registered under the interface, not its implementation
+	err = c.Register(func(ex *example) exampleInterface {
+		return newExample2(ex)
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Register(func(ex exampleInterface) *config {
+		return &config{name: ex.Text()}
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	ifaceType := reflect.TypeOf((*exampleInterface)(nil)).Elem()
+	as.Equal([]reflect.Type{reflect.TypeOf(&example{})}, c.Dependencies(ifaceType))
+	as.Equal([]reflect.Type{ifaceType}, c.Dependencies(reflect.TypeOf(&config{})))
+
+	scoped := c.Scoped()
+	var first exampleInterface
+	err = scoped.Invoke(func(ex exampleInterface, cfg *config) {
+		as.IsType(&example2{}, ex)
+		as.Equal("text", cfg.name)
+		first = ex
+	})
+	as.NoError(err)
+
+	ex, err := scoped.Get(ifaceType)
+	as.NoError(err)
+	as.Same(first, ex)
+
+	_, cached := scoped.CachedTypes()
+	as.Equal([]reflect.Type{ifaceType}, cached)
+
+	_, err = scoped.Get(reflect.TypeOf(&example2{}))
+	as.EqualError(err, "dependency *di.example2 was not registered")
+}
+
+func TestInvokeWith(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("registered")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	stub := newExample("stub")
+	called := false
+	err = c.InvokeWith(func(ex *example, ex2 *example2) {
+		called = true
+		as.Same(stub, ex)
+		// dependencies of other parameters are still resolved from the container
+		as.Equal("registered", ex2.Text())
+	}, map[reflect.Type]interface{}{
+		reflect.TypeOf(&example{}): stub,
+	})
+	as.NoError(err)
+	as.True(called)
+
+	err = c.InvokeWith(func(ex exampleInterface) {
+		as.Nil(ex)
+	}, map[reflect.Type]interface{}{
+		reflect.TypeOf((*exampleInterface)(nil)).Elem(): nil,
+	})
+	as.NoError(err)
+
+	err = c.InvokeWith(func(ex *example) {}, map[reflect.Type]interface{}{
+		reflect.TypeOf(&example2{}): newExample2(stub),
+	})
+	as.EqualError(err, "invoker has no parameter of overridden type *di.example2")
+
+	err = c.InvokeWith(func(ex *example) {}, map[reflect.Type]interface{}{
+		reflect.TypeOf(&example{}): newExample2(stub),
+	})
+	as.EqualError(err, "override of type *di.example2 can't be passed as *di.example")
+}
+
+func TestBuildSingletonError(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.RegisterWithInit(func() *example {
+		return newExample("")
+	}, Singleton, func(interface{}) error {
+		return errors.New("no connection")
+	})
+	as.NoError(err)
+
+	err = c.Build()
+	as.EqualError(err, "failed to build singleton *di.example: failed to init *di.example: no connection")
+
+	// panics are converted to errors with panic recovery
+	c = NewContainer(WithPanicRecovery())
+	err = c.Register(func() *example {
+		panic("no config")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Build()
+	as.EqualError(err, "failed to build singleton *di.example: provider of *di.example panicked: no config")
+}
+
+func TestOverrideScoped(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("singleton")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.OverrideScoped(newExample("override"))
+	as.Equal(errOverrideNotScoped, err)
+
+	scoped := c.Scoped()
+	err = scoped.OverrideScoped(newExample3())
+	as.EqualError(err, "type *di.example3 is not registered")
+
+	// containers derived before the override get it too
+	derived := scoped.WithContext("key", "value")
+	override := newExample("override")
+	err = scoped.OverrideScoped(override)
+	as.NoError(err)
+
+	err = derived.Invoke(func(ex *example, ex2 *example2) {
+		as.Same(override, ex)
+		as.Same(override, ex2.Example)
+	})
+	as.NoError(err)
+
+	err = scoped.WithContext("key", "value").Invoke(func(ex *example) {
+		as.Same(override, ex)
+	})
+	as.NoError(err)
+
+	// singleton cache and other scopes are not affected
+	err = c.Invoke(func(ex *example) {
+		as.Equal("singleton", ex.text)
+	})
+	as.NoError(err)
+
+	err = scoped.Scoped().Invoke(func(ex *example) {
+		as.Equal("singleton", ex.text)
+	})
+	as.NoError(err)
+
+	err = c.Scoped().Invoke(func(ex *example, ex2 *example2) {
+		as.Equal("singleton", ex.text)
+		as.Equal("singleton", ex2.Text())
+	})
+	as.NoError(err)
+}
@@ -0,0 +1,234 @@
+package di
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+)
+
+// RequestInfo describes the dependent that a dependency is resolved for. Providers accepting RequestInfo as an argument
+// create dependencies tailored to their consumers, e.g. loggers named after them. Such dependencies can't be cached,
+// so they must be registered as Transient and are created for each dependent even with WithSharedTransients.
+type RequestInfo struct {
+	// Consumer is the type whose provider requires the dependency, it is nil when the dependency
+	// is requested by an invoker or by Get
+	Consumer reflect.Type
+}
+
+var (
+	contextType     = reflect.TypeOf((*context.Context)(nil)).Elem()
+	requestInfoType = reflect.TypeOf(RequestInfo{})
+)
+
+// ScopedContext returns new container in request scope like Scoped does, carrying ctx. Providers and invokers
+// receive ctx if they accept context.Context argument; containers that were not created by ScopedContext pass
+// context.Background() instead. Once ctx is done, Scoped dependencies cached by the returned container are closed
+// like Close does, errors of closing them are ignored.
+func (c *Container) ScopedContext(ctx context.Context) *Container {
+	scoped := c.Scoped()
+	scoped.ctx = ctx
+	if done := ctx.Done(); done != nil {
+		go func() {
+			<-done
+			_ = scoped.Close()
+		}()
+	}
+
+	return scoped
+}
+
+// Close drops Scoped dependencies cached by container in request scope and closes the ones that implement io.Closer,
+// in reverse order of registration. Errors returned by Close are joined into one. Nested scopes don't close
+// dependencies registered with SharedInNestedScopes, as they belong to the outermost request scope.
+// Released container can't be closed, as its cache may already be used by another request scope.
+func (c *Container) Close() error {
+	if c.scope != RequestScope {
+		return nil
+	}
+
+	dropped, err := c.dropScoped()
+	if err != nil {
+		return err
+	}
+
+	return c.closeValues(dropped)
+}
+
+// dropScoped drops Scoped dependencies cached by container in request scope and returns them to be closed
+func (c *Container) dropScoped() ([]droppedValue, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.isReleased() {
+		return nil, errReleased
+	}
+
+	var dropped []droppedValue
+	// dependents are registered after their dependencies, so they are closed first
+	for i := len(c.registered) - 1; i >= 0; i-- {
+		t := c.registered[i]
+		if c.lifetimes[t] != Scoped {
+			continue
+		}
+
+		if reg, ok := c.registrations[t]; ok && reg.sharedInNestedScopes && c.nestedScope {
+			continue
+		}
+
+		cache := c.scopedCacheOf(t)
+		val, ok := cache.Get(t)
+		if !ok {
+			continue
+		}
+
+		cache.Delete(t)
+		dropped = append(dropped, droppedValue{t: t, val: val})
+	}
+
+	return dropped, nil
+}
+
+// Release drops Scoped dependencies cached by container in request scope without closing them, call Close first
+// to close them. If container was created with WithScopePool, its cache is returned to the pool and reused
+// by a container returned by the next Scoped call. Released container and containers derived from it, e.g.
+// by WithContext, fail to resolve, seed, override and close dependencies with errReleased. Nested scopes share cache of the outermost request scope,
+// so they can't be released by themselves: they are released along with it. Releasing container again does nothing.
+func (c *Container) Release() error {
+	if c.scope != RequestScope {
+		return errReleaseNotScoped
+	}
+
+	if c.nestedScope {
+		return errReleaseNested
+	}
+
+	// the lock keeps Close, SeedScoped and OverrideScoped from using the cache while it is released
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if !atomic.CompareAndSwapInt32(c.released, 0, 1) {
+		return nil
+	}
+
+	scopedCache := c.scopedCache
+	c.scopedCache = nil
+	c.requestCache = nil
+	cache, ok := scopedCache.(*syncCache)
+	if !ok {
+		// custom caches can't be reused, they only drop dependencies
+		for _, t := range c.registered {
+			if c.lifetimes[t] == Scoped {
+				scopedCache.Delete(t)
+			}
+		}
+
+		return nil
+	}
+
+	cache.clear()
+	if c.scopePool != nil {
+		c.scopePool.Put(cache)
+	}
+
+	return nil
+}
+
+// Shutdown drops cached Singleton dependencies, along with Scoped ones if container is in request scope, and closes
+// the ones that implement io.Closer. Order is derived from the dependency graph: dependents are closed before their
+// dependencies, so a service is closed before the connection it uses. Errors returned by Close are joined into one.
+func (c *Container) Shutdown() error {
+	return c.closeValues(c.dropCached())
+}
+
+// dropCached drops cached Singleton and Scoped dependencies for Shutdown and returns them to be closed
+func (c *Container) dropCached() []droppedValue {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	order := c.graph.topologicalOrder(c.registered)
+	var dropped []droppedValue
+	for i := len(order) - 1; i >= 0; i-- {
+		t := order[i]
+		var cache Cache
+		switch c.lifetimes[t] {
+		case Singleton:
+			cache = c.singletonsCache
+		case Scoped:
+			if c.scope != RequestScope || c.isReleased() {
+				continue
+			}
+
+			if reg, ok := c.registrations[t]; ok && reg.sharedInNestedScopes && c.nestedScope {
+				continue
+			}
+
+			cache = c.scopedCacheOf(t)
+		default:
+			continue
+		}
+
+		val, ok := cache.Get(t)
+		if !ok {
+			continue
+		}
+
+		cache.Delete(t)
+		dropped = append(dropped, droppedValue{t: t, val: val})
+	}
+
+	return dropped
+}
+
+// contextArg returns value of t if it is one of the types that represent container's context:
+// ContextParams, context.Context or RequestInfo
+func (c *Container) contextArg(t reflect.Type) (reflect.Value, bool) {
+	switch t {
+	case contextParamsType:
+		return reflect.ValueOf(c.contextParams), true
+	case contextType:
+		return reflect.ValueOf(&c.ctx).Elem(), true
+	case requestInfoType:
+		return reflect.ValueOf(RequestInfo{Consumer: c.consumer}), true
+	default:
+		return reflect.Value{}, false
+	}
+}
+
+// acceptsRequestInfo checks if any of argTypes or fields of parameter objects among them is RequestInfo
+func acceptsRequestInfo(argTypes []reflect.Type) bool {
+	for _, t := range argTypes {
+		if t == requestInfoType {
+			return true
+		}
+
+		if isParamObject(t) {
+			for _, field := range injectedFields(t) {
+				if acceptsRequestInfo([]reflect.Type{field.Type}) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// acceptsRequestInfo checks if provider of t depends on RequestInfo
+func (c *Container) acceptsRequestInfo(t reflect.Type) bool {
+	reg, ok := c.registrations[t]
+	return ok && reg.acceptsRequestInfo
+}
+
+// consumedBy returns container that resolves argument of type argType of provider of consumer.
+// It is derived from c only if the argument depends on RequestInfo, so that it describes consumer.
+func (c *Container) consumedBy(argType, consumer reflect.Type) *Container {
+	for _, dep := range dependenciesOf(argType) {
+		if c.acceptsRequestInfo(dep) {
+			con := c.derive()
+			con.consumer = consumer
+			return con
+		}
+	}
+
+	return c
+}
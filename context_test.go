@@ -0,0 +1,460 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey struct{}
+
+func TestScopedContext(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func(ctx context.Context) *example {
+		text, _ := ctx.Value(ctxKey{}).(string)
+		return newExample(text)
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+	err = c.ScopedContext(ctx).Invoke(func(ex *example, ctx context.Context) {
+		as.Equal("request", ex.text)
+		as.Equal("request", ctx.Value(ctxKey{}))
+	})
+	as.NoError(err)
+
+	err = c.Invoke(func(ex *example, ctx context.Context) {
+		as.Equal("", ex.text)
+		as.Equal(context.Background(), ctx)
+	})
+	as.NoError(err)
+}
+
+func TestScopedContextCancel(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	closed := make(chan struct{})
+	err := c.Register(func() *closer {
+		return &closer{}
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scoped := c.ScopedContext(ctx)
+	val, err := scoped.Get(reflect.TypeOf(&closer{}))
+	as.NoError(err)
+
+	cl := val.(*closer)
+	cl.onClose = func() {
+		close(closed)
+	}
+
+	cancel()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		as.Fail("scoped dependency was not closed")
+	}
+
+	as.Equal(1, cl.closed)
+}
+
+func TestRelease(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithScopePool())
+
+	err := c.Register(func() *example {
+		return newExample("singleton")
+	}, Singleton)
+	as.NoError(err)
+	err = c.Register(newExample2, Scoped)
+	as.NoError(err)
+	as.NoError(c.Build())
+	as.Equal(errReleaseNotScoped, c.Release())
+
+	scoped := c.Scoped()
+	derived := scoped.WithContext("key", "value")
+	val, err := scoped.Get(reflect.TypeOf(&example2{}))
+	as.NoError(err)
+	released := val.(*example2)
+
+	as.NoError(scoped.Release())
+	as.NoError(scoped.Release())
+
+	// released container and containers derived from it can't be used
+	err = scoped.Invoke(func(ex2 *example2) {})
+	as.Equal(errReleased, err)
+	_, err = derived.Get(reflect.TypeOf(&example2{}))
+	as.Equal(errReleased, err)
+	_, err = Resolve[*example2](derived)
+	as.Equal(errReleased, err)
+
+	// reused cache doesn't keep dependencies of the released container
+	next := c.Scoped()
+	val, err = next.Get(reflect.TypeOf(&example2{}))
+	as.NoError(err)
+	as.NotSame(released, val)
+	as.Same(released.Example, val.(*example2).Example)
+	as.NoError(next.Release())
+}
+
+func TestReleaseScopedContext(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithScopePool())
+
+	err := c.Register(func() *closer {
+		return &closer{}
+	}, Scoped)
+	as.NoError(err)
+	as.NoError(c.Build())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	released := c.ScopedContext(ctx)
+	as.NoError(released.Release())
+
+	next := c.Scoped()
+	val, err := next.Get(reflect.TypeOf(&closer{}))
+	as.NoError(err)
+
+	// closing the released scope once its context is done doesn't close dependencies of the next one
+	cancel()
+	as.Equal(errReleased, released.Close())
+	as.Equal(errReleased, released.WithContext("key", "value").Close())
+	cached, err := next.Get(reflect.TypeOf(&closer{}))
+	as.NoError(err)
+	as.Same(val, cached)
+	as.Equal(0, val.(*closer).closed)
+
+	_, scoped := released.CachedTypes()
+	as.Empty(scoped)
+	as.NoError(next.Release())
+}
+
+func TestReleaseSeed(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithScopePool())
+
+	err := c.Register(func() *example {
+		return newExample("provided")
+	}, Scoped)
+	as.NoError(err)
+	as.NoError(c.Build())
+
+	released := c.Scoped()
+	as.NoError(released.Release())
+	as.Equal(errReleased, released.SeedScoped(newExample("seeded")))
+	as.Equal(errReleased, released.OverrideScoped(newExample("overridden")))
+
+	// values are not injected into the next request
+	next, err := c.Scoped().Get(reflect.TypeOf(&example{}))
+	as.NoError(err)
+	as.Equal("provided", next.(*example).text)
+}
+
+func TestReleaseNested(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithScopePool())
+
+	err := c.Register(func() *example {
+		return newExample("scoped")
+	}, Scoped, SharedInNestedScopes())
+	as.NoError(err)
+	as.NoError(c.Build())
+
+	scoped := c.Scoped()
+	nested := scoped.Scoped()
+	val, err := nested.Get(reflect.TypeOf(&example{}))
+	as.NoError(err)
+	as.Equal(errReleaseNested, nested.Release())
+
+	// nested scope is released with the outermost one, so it can't leak into the scope reusing its cache
+	as.NoError(scoped.Release())
+	_, err = nested.Get(reflect.TypeOf(&example{}))
+	as.Equal(errReleased, err)
+
+	next, err := c.Scoped().Scoped().Get(reflect.TypeOf(&example{}))
+	as.NoError(err)
+	as.NotSame(val, next)
+}
+
+func TestReleaseCustomCache(t *testing.T) {
+	as := assert.New(t)
+	cache := newSyncCache()
+	c := NewContainer(WithScopedCache(func() Cache {
+		return &countingCache{Cache: cache}
+	}))
+
+	err := c.Register(newExample3, Scoped)
+	as.NoError(err)
+	as.NoError(c.Build())
+
+	scoped := c.Scoped()
+	err = scoped.Invoke(func(*example3) {})
+	as.NoError(err)
+	_, ok := cache.Get(reflect.TypeOf(&example3{}))
+	as.True(ok)
+
+	as.NoError(scoped.Release())
+	_, ok = cache.Get(reflect.TypeOf(&example3{}))
+	as.False(ok)
+}
+
+func TestClose(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	order := make([]string, 0)
+	err := c.Register(func() *closer {
+		return &closer{err: errors.New("close failed"), onClose: func() {
+			order = append(order, "closer")
+		}}
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Register(func(cl *closer) *namedCloser {
+		return &namedCloser{onClose: func() {
+			order = append(order, "namedCloser")
+		}}
+	}, Scoped, SharedInNestedScopes())
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	// nothing is cached in main scope
+	as.NoError(c.Close())
+
+	scoped := c.Scoped()
+	nested := scoped.Scoped()
+	err = nested.Invoke(func(*namedCloser) {})
+	as.NoError(err)
+
+	// shared dependency belongs to the outermost scope, its dependency belongs to the nested one
+	err = nested.Close()
+	as.EqualError(err, "failed to close *di.closer: close failed")
+	as.Equal([]string{"closer"}, order)
+
+	order = order[:0]
+	err = scoped.Invoke(func(*closer) {})
+	as.NoError(err)
+
+	err = scoped.Close()
+	as.EqualError(err, "failed to close *di.closer: close failed")
+	as.Equal([]string{"namedCloser", "closer"}, order)
+
+	// closed dependencies are dropped from cache
+	err = scoped.Close()
+	as.NoError(err)
+}
+
+func TestCloseUnlocked(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	closing := make(chan struct{})
+	release := make(chan struct{})
+	err := c.Register(func() *closer {
+		return &closer{onClose: func() {
+			close(closing)
+			<-release
+		}}
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	slow := c.Scoped()
+	err = slow.Invoke(func(*closer) {})
+	as.NoError(err)
+
+	done := make(chan error)
+	go func() {
+		done <- slow.Close()
+	}()
+
+	// slow closer doesn't block other scopes
+	<-closing
+	err = c.Scoped().Close()
+	as.NoError(err)
+	err = c.Scoped().SeedScoped(&closer{})
+	as.NoError(err)
+
+	close(release)
+	as.NoError(<-done)
+}
+
+func TestShutdown(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	order := make([]string, 0)
+	// dependent is registered before its dependency, yet it is closed first
+	err := c.Register(func(cl *closer) *namedCloser {
+		return &namedCloser{onClose: func() {
+			order = append(order, "namedCloser")
+		}}
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func() *closer {
+		return &closer{err: errors.New("close failed"), onClose: func() {
+			order = append(order, "closer")
+		}}
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Shutdown()
+	as.EqualError(err, "failed to close *di.closer: close failed")
+	as.Equal([]string{"namedCloser", "closer"}, order)
+
+	// closed dependencies are dropped from cache
+	order = order[:0]
+	err = c.Shutdown()
+	as.NoError(err)
+	as.Empty(order)
+}
+
+func TestShutdownClosesInstanceOnce(t *testing.T) {
+	as := assert.New(t)
+
+	for _, lifetime := range []Lifetime{Singleton, Scoped} {
+		c := NewContainer()
+		cl := &closer{}
+		// the instance is cached under its own type and under each interface
+		err := c.RegisterAsMany(func() *closer {
+			return cl
+		}, []interface{}{new(io.Closer), new(interface{ Close() error })}, lifetime)
+		as.NoError(err)
+		as.NoError(c.Build())
+
+		scoped := c.Scoped()
+		err = scoped.Invoke(func(*closer, io.Closer, interface{ Close() error }) {})
+		as.NoError(err)
+
+		if lifetime == Scoped {
+			as.NoError(scoped.Close())
+		} else {
+			as.NoError(scoped.Shutdown())
+		}
+
+		as.Equal(1, cl.closed, lifetime.String())
+	}
+}
+
+type namedCloser struct {
+	onClose func()
+}
+
+func (n *namedCloser) Close() error {
+	n.onClose()
+	return nil
+}
+
+type clock func() time.Time
+
+func TestClockFromContext(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func(params ContextParams) time.Time {
+		if now, ok := ContextValue[time.Time](params, "now"); ok {
+			return now
+		}
+
+		return time.Now()
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(params ContextParams) clock {
+		if now, ok := ContextValue[time.Time](params, "now"); ok {
+			return func() time.Time {
+				return now
+			}
+		}
+
+		return time.Now
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	fixed := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	err = c.Scoped().WithContext("now", fixed).Invoke(func(now time.Time, clock clock) {
+		as.Equal(fixed, now)
+		as.Equal(fixed, clock())
+	})
+	as.NoError(err)
+
+	err = c.Scoped().Invoke(func(now time.Time, clock clock) {
+		as.NotEqual(fixed, now)
+		as.WithinDuration(time.Now(), now, time.Minute)
+		as.WithinDuration(time.Now(), clock(), time.Minute)
+	})
+	as.NoError(err)
+}
+
+func TestRequestInfo(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithSharedTransients())
+
+	// logger is named after the type that depends on it
+	err := c.Register(func(info RequestInfo) *config {
+		if info.Consumer == nil {
+			return &config{name: "root"}
+		}
+
+		return &config{name: info.Consumer.String()}
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(cfg *config) *example {
+		return newExample(cfg.name)
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(cfg *config, ex *example) *example2 {
+		return newExample2(newExample(cfg.name))
+	}, Transient)
+	as.NoError(err)
+
+	// generic providers receive their consumer as well
+	err = Provide1(c, func(cfg *config) *dependsOnExample {
+		return &dependsOnExample{Example: newExample(cfg.name)}
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(cfg *config, ex *example, ex2 *example2, dep *dependsOnExample) {
+		as.Equal("root", cfg.name)
+		as.Equal("*di.example", ex.text)
+		as.Equal("*di.example2", ex2.Example.text)
+		as.Equal("*di.dependsOnExample", dep.Example.text)
+	})
+	as.NoError(err)
+
+	err = c.Register(func(info RequestInfo) *example3 {
+		return newExample3()
+	}, Singleton)
+	as.EqualError(err, "dependency *di.example3 accepting RequestInfo must be transient")
+}
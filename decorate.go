@@ -0,0 +1,97 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var errDecoratorOptions = errors.New("registration options can't be applied to decorators")
+
+// registerDecorator registers provider of outType that accepts outType itself as a decorator: the argument
+// is resolved with the previous registration of outType and the result replaces it for all dependents.
+// Decorators of the same type are applied in order of registration, so the last one is the outermost.
+// Decorated type keeps its lifetime, so that decorator must have the same one. Cached singletons of the decorated
+// type and of its dependents are evicted, so that they are created again with the decorator by the next Build.
+func (c *Container) registerDecorator(provider interface{}, outType reflect.Type, argTypes []reflect.Type, lifetime Lifetime,
+	init func(interface{}) error, opts []RegisterOption) error {
+	reg := &registration{}
+	for _, opt := range opts {
+		opt(reg)
+	}
+
+	// token of RegisterToken is the only option that applies to the decorated type
+	if len(opts) > 1 || len(opts) == 1 && reg.token == "" {
+		return errDecoratorOptions
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.frozen() {
+		return ErrContainerFrozen
+	}
+
+	if err := c.checkToken(reg.token); err != nil {
+		return err
+	}
+
+	inner := c.constructors[outType]
+	if inner == nil {
+		return fmt.Errorf("dependency %s must be registered before its decorator", typeName(outType))
+	}
+
+	if c.lifetimes[outType] != lifetime {
+		return fmt.Errorf("decorator of %s must have its lifetime %s", typeName(outType), c.lifetimes[outType])
+	}
+
+	providerValue := reflect.ValueOf(provider)
+	var constructor innerConstructor = func(con *Container) (reflect.Value, error) {
+		args := make([]reflect.Value, len(argTypes))
+		for i, argType := range argTypes {
+			var err error
+			if argType == outType {
+				args[i], err = inner(con)
+			} else {
+				args[i], err = con.consumedBy(argType, outType).resolveProviderArg(argType)
+			}
+
+			if err != nil {
+				return reflect.Value{}, providerArgError(i, argType, outType, err)
+			}
+		}
+
+		return providerValue.Call(args)[0], nil
+	}
+
+	if init != nil {
+		constructor = withInit(constructor, init)
+	}
+
+	// decorated type additionally depends on the other arguments of decorator
+	for _, argType := range argTypes {
+		if argType == outType {
+			continue
+		}
+
+		for _, dep := range dependenciesOf(argType) {
+			c.graph.addDependency(outType, dep)
+			if _, ok := c.constructors[dep]; !ok {
+				c.constructors[dep] = nil
+			}
+		}
+	}
+
+	c.constructors[outType] = constructor
+	if reg.token != "" {
+		c.tokens[reg.token] = outType
+	}
+
+	for _, t := range c.graph.withDependents(outType) {
+		if c.lifetimes[t] == Singleton {
+			c.singletonsCache.Delete(t)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package di
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecorate(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func(inner exampleInterface) exampleInterface {
+		return inner
+	}, Singleton)
+	as.EqualError(err, "dependency di.exampleInterface must be registered before its decorator")
+
+	err = c.Register(func() exampleInterface {
+		return newExample("repo")
+	}, Singleton)
+	as.NoError(err)
+
+	// decorators are applied in order of registration
+	err = c.Register(func(inner exampleInterface, ex3 *example3) exampleInterface {
+		return newExample("cached " + inner.Text())
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(inner exampleInterface) exampleInterface {
+		return newExample("logged " + inner.Text())
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(inner exampleInterface) exampleInterface {
+		return inner
+	}, Transient)
+	as.EqualError(err, "decorator of di.exampleInterface must have its lifetime Singleton")
+
+	err = c.Register(func(inner exampleInterface) exampleInterface {
+		return inner
+	}, Singleton, Trimmable())
+	as.Equal(errDecoratorOptions, err)
+
+	err = c.Register(func(ex exampleInterface) *example2 {
+		return newExample2(newExample(ex.Text()))
+	}, Transient)
+	as.NoError(err)
+
+	// decorator's dependencies are required by the decorated type
+	err = c.Build()
+	as.EqualError(err, "type *di.example3 was not registered")
+
+	err = c.Register(func() *example3 {
+		return newExample3()
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(ex exampleInterface, ex2 *example2) {
+		as.Equal("logged cached repo", ex.Text())
+		as.Equal("logged cached repo", ex2.Text())
+	})
+	as.NoError(err)
+}
+
+func TestDecorateAfterBuild(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() exampleInterface {
+		return newExample("repo")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ex exampleInterface) *example2 {
+		return newExample2(newExample(ex.Text()))
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	// cached singletons of decorated type and its dependents are created again
+	err = c.Register(func(inner exampleInterface) exampleInterface {
+		return newExample("logged " + inner.Text())
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(ex exampleInterface, ex2 *example2) {
+		as.Equal("logged repo", ex.Text())
+		as.Equal("logged repo", ex2.Text())
+	})
+	as.NoError(err)
+}
+
+func TestDecorateGeneric(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := Provide1(c, func(inner exampleInterface) exampleInterface {
+		return inner
+	}, Singleton)
+	as.EqualError(err, "dependency di.exampleInterface must be registered before its decorator")
+
+	err = Provide0(c, func() exampleInterface {
+		return newExample("repo")
+	}, Singleton)
+	as.NoError(err)
+
+	err = Provide1(c, func(inner exampleInterface) exampleInterface {
+		return newExample("logged " + inner.Text())
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	ex, err := Resolve[exampleInterface](c)
+	as.NoError(err)
+	as.Equal("logged repo", ex.Text())
+}
@@ -0,0 +1,28 @@
+// Package ditest provides helpers for tests of code wired with di containers
+package ditest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lebedevars/di"
+)
+
+// AssertResolves resolves T from c and fails the test if it can't be resolved or is not equal to want.
+// Values are compared with reflect.DeepEqual, so pointers are equal if they point to equal values.
+func AssertResolves[T any](t testing.TB, c *di.Container, want T) bool {
+	t.Helper()
+
+	got, err := di.Resolve[T](c)
+	if err != nil {
+		t.Errorf("failed to resolve %s: %s", reflect.TypeOf((*T)(nil)).Elem(), err)
+		return false
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolved %s is not equal to expected one:\ngot:  %#v\nwant: %#v", reflect.TypeOf((*T)(nil)).Elem(), got, want)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,48 @@
+package ditest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lebedevars/di"
+)
+
+type service struct {
+	name string
+}
+
+// recorder records failures instead of failing the test
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertResolves(t *testing.T) {
+	as := assert.New(t)
+	c := di.NewContainer()
+
+	err := c.Register(func() *service {
+		return &service{name: "service"}
+	}, di.Singleton)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	as.True(AssertResolves(t, c, &service{name: "service"}))
+
+	rec := &recorder{TB: t}
+	as.False(AssertResolves(rec, c, &service{name: "other"}))
+	as.False(AssertResolves(rec, c, "text"))
+	as.Len(rec.errors, 2)
+	as.Contains(rec.errors[0], "resolved *ditest.service is not equal to expected one")
+	as.Equal("failed to resolve string: dependency string was not registered", rec.errors[1])
+}
@@ -0,0 +1,126 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// EventKind identifies a phase of container's lifecycle
+type EventKind int
+
+const (
+	// EventRegistered is emitted when a provider is registered
+	EventRegistered EventKind = iota
+	// EventBuilt is emitted when Build succeeds, Duration is the time Build took
+	EventBuilt
+	// EventCycleDetected is emitted when Build finds cyclic dependencies, Err describes them
+	EventCycleDetected
+	// EventResolved is emitted when a dependency is constructed, cached values don't emit it
+	EventResolved
+	// EventClosed is emitted when a cached dependency that implements io.Closer is closed
+	EventClosed
+)
+
+// Event describes a single lifecycle event of container
+type Event struct {
+	Kind EventKind
+	// Type is the type of dependency the event concerns, it is nil for events of the whole container
+	Type reflect.Type
+	// Duration is the time the phase took, it is zero for events that don't take time
+	Duration time.Duration
+	// Err is the error the phase ended with
+	Err error
+}
+
+var errNilEventHandler = errors.New("event handler must not be nil")
+
+// String returns the name of event kind
+func (kind EventKind) String() string {
+	switch kind {
+	case EventRegistered:
+		return "Registered"
+	case EventBuilt:
+		return "Built"
+	case EventCycleDetected:
+		return "CycleDetected"
+	case EventResolved:
+		return "Resolved"
+	case EventClosed:
+		return "Closed"
+	default:
+		return fmt.Sprintf("EventKind(%d)", int(kind))
+	}
+}
+
+// OnEvent adds handler that receives lifecycle events of container: registrations, builds, detected cycles,
+// construction and closing of dependencies. Handlers are called synchronously in the goroutine that caused
+// the event, some of them while the container is locked, so they must not call the container.
+func (c *Container) OnEvent(handler func(Event)) error {
+	if handler == nil {
+		return errNilEventHandler
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	// derived containers must not share handlers added after they were created
+	c.eventHandlers = append(c.eventHandlers[:len(c.eventHandlers):len(c.eventHandlers)], handler)
+	return nil
+}
+
+// emit passes event to all event handlers
+func (c *Container) emit(event Event) {
+	for _, handler := range c.eventHandlers {
+		handler(event)
+	}
+}
+
+// droppedValue is a dependency dropped from cache, it is closed once the container is unlocked,
+// so that Close of other containers sharing the lock is not blocked by slow closers
+type droppedValue struct {
+	t   reflect.Type
+	val reflect.Value
+}
+
+// closeValues closes dropped values in order and joins errors returned by Close into one
+func (c *Container) closeValues(dropped []droppedValue) error {
+	closed := make(map[interface{}]bool)
+	errs := make([]string, 0)
+	for _, d := range dropped {
+		if err := c.closeValue(d.t, d.val, closed); err != nil {
+			errs = append(errs, fmt.Sprintf("failed to close %s: %s", typeName(d.t), err))
+		}
+	}
+
+	if len(errs) != 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
+
+// closeValue closes val of type t if it implements io.Closer and emits EventClosed. Instances cached under several
+// types, e.g. bound to interfaces, are closed once: closed holds instances already closed by the same call.
+func (c *Container) closeValue(t reflect.Type, val reflect.Value, closed map[interface{}]bool) error {
+	closer, ok := val.Interface().(io.Closer)
+	if !ok {
+		return nil
+	}
+
+	if reflect.TypeOf(closer).Comparable() {
+		if closed[closer] {
+			return nil
+		}
+
+		closed[closer] = true
+	}
+
+	start := time.Now()
+	err := closer.Close()
+	c.emit(Event{Kind: EventClosed, Type: t, Duration: time.Since(start), Err: err})
+	return err
+}
@@ -0,0 +1,77 @@
+package di
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnEvent(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.OnEvent(nil)
+	as.Equal(errNilEventHandler, err)
+
+	events := make([]Event, 0)
+	err = c.OnEvent(func(event Event) {
+		events = append(events, event)
+	})
+	as.NoError(err)
+
+	err = c.Register(func() *closer {
+		return &closer{}
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(cl *closer) *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(ex *example) {})
+	as.NoError(err)
+
+	err = c.Shutdown()
+	as.NoError(err)
+
+	closerType := reflect.TypeOf(&closer{})
+	exampleType := reflect.TypeOf(&example{})
+	kinds := make([]EventKind, len(events))
+	types := make([]reflect.Type, len(events))
+	for i, event := range events {
+		kinds[i] = event.Kind
+		types[i] = event.Type
+	}
+
+	as.Equal([]EventKind{EventRegistered, EventRegistered, EventResolved, EventBuilt, EventResolved, EventClosed}, kinds)
+	as.Equal([]reflect.Type{closerType, exampleType, closerType, nil, exampleType, closerType}, types)
+
+	c = NewContainer()
+	err = c.OnEvent(func(event Event) {
+		events = append(events, event)
+	})
+	as.NoError(err)
+
+	err = c.Register(func(ex2 *example2) *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	events = events[:0]
+	err = c.Build()
+	as.Error(err)
+	as.Len(events, 1)
+	as.Equal(EventCycleDetected, events[0].Kind)
+	as.Equal(err, events[0].Err)
+	as.Equal("CycleDetected", events[0].Kind.String())
+}
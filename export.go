@@ -0,0 +1,120 @@
+package di
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// registrationInfo describes a single registration in ExportJSON output
+type registrationInfo struct {
+	Type         string   `json:"type"`
+	Package      string   `json:"package"`
+	Name         string   `json:"name,omitempty"`
+	Lifetime     string   `json:"lifetime"`
+	Dependencies []string `json:"dependencies"`
+	Cached       bool     `json:"cached"`
+}
+
+// ExportJSON describes all registrations of the container in JSON: fully-qualified type name, its package,
+// name of named dependencies, lifetime, direct dependencies and whether the instance is currently cached.
+// Registrations are sorted by type name, so that the output of the same wiring is always the same.
+func (c *Container) ExportJSON() ([]byte, error) {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	infos := make([]registrationInfo, 0, len(c.constructors))
+	for t, constructor := range c.constructors {
+		// types that are only required by others are not registered
+		if constructor == nil {
+			continue
+		}
+
+		info := registrationInfo{
+			Type:         qualifiedName(t),
+			Package:      packagePath(t),
+			Lifetime:     c.lifetimes[t].String(),
+			Dependencies: make([]string, 0),
+		}
+
+		if namedT, name, ok := parseNamedType(t); ok {
+			info.Type = qualifiedName(namedT)
+			info.Package = packagePath(namedT)
+			info.Name = name
+		} else if groupT, _, ok := parseGroupType(t); ok {
+			info.Type = qualifiedTypeName(t)
+			info.Package = packagePath(groupT)
+		}
+
+		for _, dep := range c.dependencies(t) {
+			info.Dependencies = append(info.Dependencies, qualifiedTypeName(dep))
+		}
+
+		info.Cached = c.isCached(t)
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Type != infos[j].Type {
+			return infos[i].Type < infos[j].Type
+		}
+
+		return infos[i].Name < infos[j].Name
+	})
+
+	return json.Marshal(infos)
+}
+
+// qualifiedTypeName returns the name of t like typeName does, but with full package paths
+func qualifiedTypeName(t reflect.Type) string {
+	if namedT, name, ok := parseNamedType(t); ok {
+		return fmt.Sprintf("%s named %q", qualifiedName(namedT), name)
+	}
+
+	if groupT, index, ok := parseGroupType(t); ok {
+		return fmt.Sprintf("%s in group (#%d)", qualifiedName(groupT), index)
+	}
+
+	return qualifiedName(t)
+}
+
+// qualifiedName returns the name of t with full package paths of named types instead of package names
+func qualifiedName(t reflect.Type) string {
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			return t.Name()
+		}
+
+		return t.PkgPath() + "." + t.Name()
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + qualifiedName(t.Elem())
+	case reflect.Slice:
+		return "[]" + qualifiedName(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), qualifiedName(t.Elem()))
+	case reflect.Map:
+		return fmt.Sprintf("map[%s]%s", qualifiedName(t.Key()), qualifiedName(t.Elem()))
+	case reflect.Chan:
+		return t.ChanDir().String() + " " + qualifiedName(t.Elem())
+	default:
+		return t.String()
+	}
+}
+
+// packagePath returns path of the package where t or the type it is composed of is declared
+func packagePath(t reflect.Type) string {
+	for t.Name() == "" {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Chan, reflect.Map:
+			t = t.Elem()
+		default:
+			return ""
+		}
+	}
+
+	return t.PkgPath()
+}
@@ -0,0 +1,64 @@
+package di
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportJSON(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example, params ContextParams) *example2 {
+		return newExample2(ex)
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.RegisterNamed("first", func(ex2 *example2) exampleInterface {
+		return ex2
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	data, err := c.ExportJSON()
+	as.NoError(err)
+	as.JSONEq(`[
+		{
+			"type": "*github.com/lebedevars/di.example",
+			"package": "github.com/lebedevars/di",
+			"lifetime": "Singleton",
+			"dependencies": [],
+			"cached": true
+		},
+		{
+			"type": "*github.com/lebedevars/di.example2",
+			"package": "github.com/lebedevars/di",
+			"lifetime": "Scoped",
+			"dependencies": ["*github.com/lebedevars/di.example"],
+			"cached": false
+		},
+		{
+			"type": "github.com/lebedevars/di.exampleInterface",
+			"package": "github.com/lebedevars/di",
+			"name": "first",
+			"lifetime": "Transient",
+			"dependencies": ["*github.com/lebedevars/di.example2"],
+			"cached": false
+		},
+		{
+			"type": "map[string]github.com/lebedevars/di.exampleInterface",
+			"package": "github.com/lebedevars/di",
+			"lifetime": "Transient",
+			"dependencies": ["github.com/lebedevars/di.exampleInterface named \"first\""],
+			"cached": false
+		}
+	]`, string(data))
+}
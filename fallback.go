@@ -0,0 +1,72 @@
+package di
+
+import (
+	"errors"
+	"reflect"
+)
+
+var (
+	errNilFallback    = errors.New("fallback container must not be nil")
+	errCyclicFallback = errors.New("fallback container falls back to the container itself")
+)
+
+// Fallback adds parent to containers that resolve dependencies not registered in c, e.g. to layer application
+// providers on top of a container shipped by a library. Local registrations are tried first, then fallbacks in
+// order they were added. Unlike containers returned by Scoped, parent is an independent container: it must be built
+// by itself and keeps its own caches, so its dependencies are resolved as by parent itself, in its scope and context.
+// Fallback must be called before Build of c, so that dependencies provided by parent are not reported as missing.
+// Containers derived from c, e.g. by Scoped, share its registrations and can't be its fallbacks.
+func (c *Container) Fallback(parent *Container) error {
+	if parent == nil {
+		return errNilFallback
+	}
+
+	if parent.m == c.m || parent.fallsBackTo(c) {
+		return errCyclicFallback
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.fallbacks = append(c.fallbacks, parent)
+	return nil
+}
+
+// fallsBackTo checks if c transitively falls back to container other or to one derived from it
+func (c *Container) fallsBackTo(other *Container) bool {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	for _, fallback := range c.fallbacks {
+		if fallback.m == other.m || fallback.fallsBackTo(other) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fallbackFor returns the first fallback container that provides dependency of type t
+func (c *Container) fallbackFor(t reflect.Type) (*Container, bool) {
+	for _, fallback := range c.fallbacks {
+		// fallback is an independent container, so its registrations are guarded by its own lock
+		fallback.m.RLock()
+		ok := fallback.provides(t)
+		fallback.m.RUnlock()
+		if ok {
+			return fallback, true
+		}
+	}
+
+	return nil, false
+}
+
+// provides checks if c or any of its fallbacks can resolve dependency of type t
+func (c *Container) provides(t reflect.Type) bool {
+	if _, ok := c.adaptedType(t); c.constructors[t] != nil || ok {
+		return true
+	}
+
+	_, ok := c.fallbackFor(t)
+	return ok
+}
@@ -0,0 +1,119 @@
+package di
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFallback(t *testing.T) {
+	as := assert.New(t)
+
+	base := NewContainer()
+	as.NoError(base.Register(func() *example {
+		return newExample("base")
+	}, Singleton))
+	as.NoError(base.Register(newExample3, Transient))
+	as.NoError(base.Build())
+
+	app := NewContainer()
+	as.NoError(app.Fallback(base))
+	// local registrations are tried first
+	as.NoError(app.Register(func() *example3 {
+		return &example3{}
+	}, Singleton))
+	as.NoError(app.Register(newExample2, Scoped))
+	as.NoError(app.Build())
+	as.Empty(app.PendingTypes())
+	as.NoError(app.Resolvable(reflect.TypeOf(&example2{})))
+
+	ex3, err := app.Get(reflect.TypeOf(&example3{}))
+	as.NoError(err)
+	ex3Again, err := app.Get(reflect.TypeOf(&example3{}))
+	as.NoError(err)
+	as.Same(ex3, ex3Again)
+
+	// base keeps its own singletons
+	baseEx, err := base.Get(reflect.TypeOf(&example{}))
+	as.NoError(err)
+	err = app.Scoped().Invoke(func(ex *example, ex2 *example2) {
+		as.Same(baseEx, ex)
+		as.Same(baseEx, ex2.Example)
+	})
+	as.NoError(err)
+}
+
+func TestFallbackOrder(t *testing.T) {
+	as := assert.New(t)
+
+	first := NewContainer()
+	as.NoError(first.Register(func() *example {
+		return newExample("first")
+	}, Transient))
+	as.NoError(first.Build())
+
+	second := NewContainer()
+	as.NoError(second.Register(func() *example {
+		return newExample("second")
+	}, Transient))
+	as.NoError(second.Register(newExample3, Transient))
+	as.NoError(second.Build())
+
+	c := NewContainer()
+	as.NoError(c.Fallback(first))
+	as.NoError(c.Fallback(second))
+	as.NoError(c.Build())
+
+	err := c.Invoke(func(ex *example, ex3 *example3) {
+		as.Equal("first", ex.text)
+		as.NotNil(ex3)
+	})
+	as.NoError(err)
+}
+
+func TestFallbackErrors(t *testing.T) {
+	as := assert.New(t)
+
+	c := NewContainer()
+	as.Equal(errNilFallback, c.Fallback(nil))
+	as.Equal(errCyclicFallback, c.Fallback(c))
+	as.Equal(errCyclicFallback, c.Fallback(c.Scoped()))
+
+	parent := NewContainer()
+	as.NoError(c.Fallback(parent))
+	as.Equal(errCyclicFallback, parent.Fallback(c))
+
+	// dependencies missing in fallbacks are still reported
+	as.NoError(c.Register(newExample2, Transient))
+	as.EqualError(c.Build(), "type *di.example was not registered")
+	as.EqualError(c.Resolvable(reflect.TypeOf(&example2{})),
+		"dependency *di.example was not registered, resolution path: *di.example2 -> *di.example")
+}
+
+func TestFallbackConcurrentRegister(t *testing.T) {
+	as := assert.New(t)
+
+	base := NewContainer()
+	app := NewContainer()
+	as.NoError(app.Fallback(base))
+	as.NoError(app.Build())
+
+	// parent's registrations are read under its lock while it is changed
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		as.NoError(base.Register(func() *example {
+			return newExample("base")
+		}, Transient))
+	}()
+
+	for i := 0; i < 100; i++ {
+		_, err := app.Get(reflect.TypeOf(&example2{}))
+		as.EqualError(err, "dependency *di.example2 was not registered")
+	}
+
+	wg.Wait()
+}
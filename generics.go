@@ -0,0 +1,247 @@
+package di
+
+import (
+	"reflect"
+)
+
+// Provide0 registers provider without arguments. Unlike Register, provider is called directly, without reflection.
+func Provide0[T any](c *Container, provider func() T, lifetime Lifetime, opts ...RegisterOption) error {
+	if provider == nil {
+		return errNilProvider
+	}
+
+	return c.registerConstructor(typeOf[T](), nil, func(*Container) (reflect.Value, error) {
+		return valueOf(provider()), nil
+	}, lifetime, opts)
+}
+
+// RegisterValue registers value as a singleton of type T without a provider, e.g. a config value of a named type.
+// Registering a value of the type that is already registered fails like any double registration.
+func RegisterValue[T any](c *Container, value T, opts ...RegisterOption) error {
+	val := valueOf(value)
+	return c.registerConstructor(typeOf[T](), nil, func(*Container) (reflect.Value, error) {
+		return val, nil
+	}, Singleton, opts)
+}
+
+// Provide1 registers provider with one argument. Unlike Register, provider is called directly, without reflection.
+func Provide1[A, T any](c *Container, provider func(A) T, lifetime Lifetime, opts ...RegisterOption) error {
+	if provider == nil {
+		return errNilProvider
+	}
+
+	argTypes := []reflect.Type{typeOf[A]()}
+	outType := typeOf[T]()
+	return c.registerGeneric(provider, outType, argTypes, func(con *Container) (reflect.Value, error) {
+		a, err := resolveAs[A](con, argTypes, 0, outType)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		return valueOf(provider(a)), nil
+	}, lifetime, opts)
+}
+
+// Provide2 registers provider with two arguments. Unlike Register, provider is called directly, without reflection.
+func Provide2[A, B, T any](c *Container, provider func(A, B) T, lifetime Lifetime, opts ...RegisterOption) error {
+	if provider == nil {
+		return errNilProvider
+	}
+
+	argTypes := []reflect.Type{typeOf[A](), typeOf[B]()}
+	outType := typeOf[T]()
+	return c.registerGeneric(provider, outType, argTypes, func(con *Container) (reflect.Value, error) {
+		a, err := resolveAs[A](con, argTypes, 0, outType)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		b, err := resolveAs[B](con, argTypes, 1, outType)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		return valueOf(provider(a, b)), nil
+	}, lifetime, opts)
+}
+
+// Provide3 registers provider with three arguments. Unlike Register, provider is called directly, without reflection.
+func Provide3[A, B, C, T any](c *Container, provider func(A, B, C) T, lifetime Lifetime, opts ...RegisterOption) error {
+	if provider == nil {
+		return errNilProvider
+	}
+
+	argTypes := []reflect.Type{typeOf[A](), typeOf[B](), typeOf[C]()}
+	outType := typeOf[T]()
+	return c.registerGeneric(provider, outType, argTypes, func(con *Container) (reflect.Value, error) {
+		a, err := resolveAs[A](con, argTypes, 0, outType)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		b, err := resolveAs[B](con, argTypes, 1, outType)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		cc, err := resolveAs[C](con, argTypes, 2, outType)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		return valueOf(provider(a, b, cc)), nil
+	}, lifetime, opts)
+}
+
+// Invoke1 calls invoker with one resolved argument. Unlike Invoke, invoker is called directly, without reflection.
+func Invoke1[A any](c *Container, invoker func(A)) error {
+	if err := c.checkUsable(); err != nil {
+		return err
+	}
+
+	if invoker == nil {
+		return errNilInvoker
+	}
+
+	con := c.forCall()
+	a, err := getAs[A](con, 0)
+	if err != nil {
+		return err
+	}
+
+	invoker(a)
+	return nil
+}
+
+// Invoke2 calls invoker with two resolved arguments. Unlike Invoke, invoker is called directly, without reflection.
+func Invoke2[A, B any](c *Container, invoker func(A, B)) error {
+	if err := c.checkUsable(); err != nil {
+		return err
+	}
+
+	if invoker == nil {
+		return errNilInvoker
+	}
+
+	con := c.forCall()
+	a, err := getAs[A](con, 0)
+	if err != nil {
+		return err
+	}
+
+	b, err := getAs[B](con, 1)
+	if err != nil {
+		return err
+	}
+
+	invoker(a, b)
+	return nil
+}
+
+// Invoke3 calls invoker with three resolved arguments. Unlike Invoke, invoker is called directly, without reflection.
+func Invoke3[A, B, C any](c *Container, invoker func(A, B, C)) error {
+	if err := c.checkUsable(); err != nil {
+		return err
+	}
+
+	if invoker == nil {
+		return errNilInvoker
+	}
+
+	con := c.forCall()
+	a, err := getAs[A](con, 0)
+	if err != nil {
+		return err
+	}
+
+	b, err := getAs[B](con, 1)
+	if err != nil {
+		return err
+	}
+
+	cc, err := getAs[C](con, 2)
+	if err != nil {
+		return err
+	}
+
+	invoker(a, b, cc)
+	return nil
+}
+
+// Resolve returns dependency of type T like Get does, but typed
+func Resolve[T any](c *Container) (T, error) {
+	var res T
+	if err := c.checkUsable(); err != nil {
+		return res, err
+	}
+
+	val, err := c.forCall().getValue(typeOf[T]())
+	if err != nil {
+		return res, err
+	}
+
+	// nil interface values can't be asserted, zero T is returned for them
+	res, _ = val.Interface().(T)
+	return res, nil
+}
+
+// RegisterContextLogger registers logger of type L created by factory from context of container as a Scoped
+// dependency, so that each request gets its own logger tagged with values of its context, e.g. request ID.
+func RegisterContextLogger[L any](c *Container, factory func(ContextParams) L) error {
+	return Provide1(c, factory, Scoped)
+}
+
+// ContextValue returns value of params by key as T. Unlike type assertion of GetValue result, it does not panic:
+// ok is false if there is no value or it is not of type T.
+func ContextValue[T any](params ContextParams, key string) (T, bool) {
+	val, ok := params[key].(T)
+	return val, ok
+}
+
+// typeOf returns reflect.Type of T, including interface types
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// valueOf returns reflect.Value of type T holding val
+func valueOf[T any](val T) reflect.Value {
+	return reflect.ValueOf(&val).Elem()
+}
+
+// registerGeneric registers constructor of generic provider, provider that accepts its out-parameter type is registered
+// as a decorator like Register does. Decorators are called with reflection, as they need the previous registration.
+func (c *Container) registerGeneric(provider interface{}, outType reflect.Type, argTypes []reflect.Type,
+	constructor innerConstructor, lifetime Lifetime, opts []RegisterOption) error {
+	if containsType(argTypes, outType) {
+		return c.registerDecorator(provider, outType, argTypes, lifetime, nil, opts)
+	}
+
+	return c.registerConstructor(outType, argTypes, constructor, lifetime, opts)
+}
+
+// resolveAs resolves index-th argument of provider of outType and converts it to T
+func resolveAs[T any](con *Container, argTypes []reflect.Type, index int, outType reflect.Type) (T, error) {
+	var res T
+	val, err := con.consumedBy(argTypes[index], outType).resolveProviderArg(argTypes[index])
+	if err != nil {
+		return res, providerArgError(index, argTypes[index], outType, err)
+	}
+
+	// nil interface values can't be asserted, zero T is returned for them
+	res, _ = val.Interface().(T)
+	return res, nil
+}
+
+// getAs resolves index-th argument of invoker of type T like Invoke does
+func getAs[T any](con *Container, index int) (T, error) {
+	var res T
+	t := typeOf[T]()
+	val, err := con.getValue(t)
+	if err != nil {
+		return res, invokerArgError(index, t, err)
+	}
+
+	// nil interface values can't be asserted, zero T is returned for them
+	res, _ = val.Interface().(T)
+	return res, nil
+}
@@ -0,0 +1,383 @@
+package di
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvide(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := Provide0(c, func() *example {
+		return newExample("I was injected")
+	}, Singleton)
+	as.NoError(err)
+
+	err = Provide1(c, func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = Provide2(c, func(ex *example, params ContextParams) exampleInterface {
+		return newExample(params.GetValue("text").(string))
+	}, Scoped)
+	as.NoError(err)
+
+	err = Provide3(c, func(ex *example, ex2 *example2, iface exampleInterface) *dependsOnExample {
+		as.Equal(ex, ex2.Example)
+		as.Equal("context", iface.Text())
+		return &dependsOnExample{Example: ex}
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.WithContext("text", "context").Invoke(func(dep *dependsOnExample, iface exampleInterface) {
+		as.Equal("I was injected", dep.Example.text)
+		as.Equal("context", iface.Text())
+	})
+	as.NoError(err)
+
+	val, err := c.WithContext("text", "context").Get(reflect.TypeOf((*exampleInterface)(nil)).Elem())
+	as.NoError(err)
+	as.IsType(&example{}, val)
+}
+
+func TestProvideMixedWithRegister(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("I was injected")
+	}, Transient)
+	as.NoError(err)
+
+	err = Provide1(c, func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(ex2 *example2) *dependsOnExample {
+		return &dependsOnExample{Example: ex2.Example}
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(dep *dependsOnExample) {
+		as.Equal("I was injected", dep.Example.text)
+	})
+	as.NoError(err)
+}
+
+func TestProvideErrors(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := Provide0[*example](c, nil, Transient)
+	as.EqualError(err, errNilProvider.Error())
+
+	err = Provide0(c, func() *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = Provide0(c, func() *example {
+		return newExample("")
+	}, Transient)
+	as.NotNil(err)
+	as.True(strings.HasSuffix(err.Error(), "was already registered"))
+
+	err = Provide1(c, func(ex3 *example3) *example2 {
+		return newExample2(nil)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NotNil(err)
+	as.True(strings.HasSuffix(err.Error(), "was not registered"))
+}
+
+func TestInvokeGeneric(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithSharedTransients())
+
+	err := Provide0(c, func() *example {
+		return newExample("I was injected")
+	}, Transient)
+	as.NoError(err)
+
+	err = Provide1(c, func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = Provide0(c, func() exampleInterface {
+		return nil
+	}, Transient)
+	as.NoError(err)
+
+	err = Invoke1(c, func(ex *example) {})
+	as.Equal(errMustBuildContainer, err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	called := false
+	err = Invoke3(c, func(ex *example, ex2 *example2, iface exampleInterface) {
+		called = true
+		as.Equal("I was injected", ex.text)
+		as.Same(ex, ex2.Example)
+		as.Nil(iface)
+	})
+	as.NoError(err)
+	as.True(called)
+
+	err = Invoke2(c.WithContext("key", "value"), func(params ContextParams, ex2 *example2) {
+		as.Equal("value", params.GetValue("key"))
+		as.NotNil(ex2)
+	})
+	as.NoError(err)
+
+	err = Invoke1(c, func(ex3 *example3) {})
+	as.True(strings.HasSuffix(err.Error(), "was not registered"))
+
+	err = Invoke1[*example](c, nil)
+	as.Equal(errNilInvoker, err)
+}
+
+func TestRegisterValue(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	type port int
+	err := RegisterValue(c, port(8080))
+	as.NoError(err)
+
+	err = RegisterValue[exampleInterface](c, newExample("value"))
+	as.NoError(err)
+
+	err = RegisterValue(c, port(9090))
+	as.EqualError(err, "dependency di.port was already registered")
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(p port, ex exampleInterface) {
+		as.Equal(port(8080), p)
+		as.Equal("value", ex.Text())
+	})
+	as.NoError(err)
+}
+
+func TestResolve(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := Provide0(c, func() *example {
+		return newExample("text")
+	}, Singleton)
+	as.NoError(err)
+
+	_, err = Resolve[*example](c)
+	as.Equal(errMustBuildContainer, err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	ex, err := Resolve[*example](c)
+	as.NoError(err)
+	as.Equal("text", ex.text)
+
+	iface, err := Resolve[exampleInterface](c)
+	as.EqualError(err, "dependency di.exampleInterface was not registered")
+	as.Nil(iface)
+}
+
+// testLogger holds structured fields it was created with
+type testLogger struct {
+	fields map[string]interface{}
+}
+
+func TestRegisterContextLogger(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := RegisterContextLogger(c, func(params ContextParams) *testLogger {
+		return &testLogger{fields: map[string]interface{}{"requestID": params.GetValue("requestID")}}
+	})
+	as.NoError(err)
+
+	err = c.Register(func(logger *testLogger) *config {
+		return &config{name: logger.fields["requestID"].(string)}
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	first := c.WithContext("requestID", "first").Scoped()
+	second := c.WithContext("requestID", "second").Scoped()
+	err = first.Invoke(func(logger *testLogger, cfg *config) {
+		as.Equal("first", logger.fields["requestID"])
+		as.Equal("first", cfg.name)
+	})
+	as.NoError(err)
+
+	err = second.Invoke(func(logger *testLogger) {
+		as.Equal("second", logger.fields["requestID"])
+	})
+	as.NoError(err)
+
+	// logger is created once per scope
+	logger1, err := Resolve[*testLogger](first)
+	as.NoError(err)
+	logger2, err := Resolve[*testLogger](first)
+	as.NoError(err)
+	as.Same(logger1, logger2)
+}
+
+func TestContextValue(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := Provide1(c, func(params ContextParams) *example {
+		text, ok := ContextValue[string](params, "text")
+		if !ok {
+			text = "default"
+		}
+
+		return newExample(text)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = Invoke1(c.WithContext("text", "context"), func(ex *example) {
+		as.Equal("context", ex.text)
+	})
+	as.NoError(err)
+
+	err = Invoke1(c.WithContext("text", 1), func(ex *example) {
+		as.Equal("default", ex.text)
+	})
+	as.NoError(err)
+
+	err = Invoke1(c, func(ex *example) {
+		as.Equal("default", ex.text)
+	})
+	as.NoError(err)
+
+	iface, ok := ContextValue[exampleInterface](ContextParams{"ex": newExample("")}, "ex")
+	as.True(ok)
+	as.NotNil(iface)
+}
+
+type genericCache[T any] struct {
+	values map[string]T
+}
+
+func TestGenericInstantiations(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *genericCache[string] {
+		return &genericCache[string]{values: map[string]string{"key": "value"}}
+	}, Singleton)
+	as.NoError(err)
+
+	err = Provide0(c, func() *genericCache[int] {
+		return &genericCache[int]{values: map[string]int{"key": 1}}
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Register(func(strings *genericCache[string], ints *genericCache[int]) *genericCache[*example] {
+		return &genericCache[*example]{values: map[string]*example{
+			strings.values["key"]: newExample(""),
+		}}
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func() *genericCache[string] {
+		return nil
+	}, Singleton)
+	as.EqualError(err, "dependency *di.genericCache[string] was already registered")
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = Invoke3(c, func(strings *genericCache[string], ints *genericCache[int], examples *genericCache[*example]) {
+		as.Equal("value", strings.values["key"])
+		as.Equal(1, ints.values["key"])
+		as.Contains(examples.values, "value")
+	})
+	as.NoError(err)
+
+	as.Equal(map[reflect.Type]Lifetime{
+		reflect.TypeOf(&genericCache[string]{}):   Singleton,
+		reflect.TypeOf(&genericCache[int]{}):      Scoped,
+		reflect.TypeOf(&genericCache[*example]{}): Transient,
+	}, c.Lifetimes())
+
+	data, err := c.ExportJSON()
+	as.NoError(err)
+	as.Contains(string(data), `"*github.com/lebedevars/di.genericCache[*github.com/lebedevars/di.example]"`)
+
+	_, err = c.Get(reflect.TypeOf(&genericCache[bool]{}))
+	as.EqualError(err, "dependency *di.genericCache[bool] was not registered")
+}
+
+func BenchmarkResolveGeneric(b *testing.B) {
+	as := assert.New(b)
+	b.ReportAllocs()
+
+	c := NewContainer()
+	err := Provide0(c, func() *example {
+		return newExample("I was injected")
+	}, Transient)
+	as.NoError(err)
+
+	err = Provide1(c, func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	for i := 0; i < b.N; i++ {
+		_ = c.Invoke(func(ex2 *example2) {
+		})
+	}
+}
+
+func BenchmarkInvokeGeneric(b *testing.B) {
+	as := assert.New(b)
+	b.ReportAllocs()
+
+	c := NewContainer()
+	err := Provide0(c, func() *example {
+		return newExample("I was injected")
+	}, Transient)
+	as.NoError(err)
+
+	err = Provide1(c, func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	for i := 0; i < b.N; i++ {
+		_ = Invoke1(c, func(ex2 *example2) {
+		})
+	}
+}
@@ -1,20 +1,49 @@
 package di
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 )
 
 type dependencyGraph struct {
 	deps map[reflect.Type][]reflect.Type
+	// edges holds the same dependencies as deps to check for duplicates
+	edges map[reflect.Type]map[reflect.Type]bool
+	// acyclic is set once the graph is checked for cycles and reset by any change of it,
+	// so that repeated builds of the same graph don't search for cycles again
+	acyclic bool
 }
 
 func newDependencyGraph() *dependencyGraph {
-	return &dependencyGraph{deps: make(map[reflect.Type][]reflect.Type)}
+	return &dependencyGraph{
+		deps:  make(map[reflect.Type][]reflect.Type),
+		edges: make(map[reflect.Type]map[reflect.Type]bool),
+	}
 }
 
+// addDependency adds edge from type to its dependency unless it was already added
 func (graph *dependencyGraph) addDependency(from, to reflect.Type) {
+	if graph.edges[from] == nil {
+		graph.edges[from] = make(map[reflect.Type]bool)
+	}
+
+	if graph.edges[from][to] {
+		return
+	}
+
+	graph.edges[from][to] = true
 	graph.deps[from] = append(graph.deps[from], to)
+	graph.acyclic = false
+}
+
+// remove removes type along with all of its dependencies
+func (graph *dependencyGraph) remove(t reflect.Type) {
+	delete(graph.deps, t)
+	delete(graph.edges, t)
+	graph.acyclic = false
 }
 
 // detectCyclicDependencies uses DFS to determine if the dependency graph is cyclic
@@ -23,13 +52,25 @@ func (graph *dependencyGraph) detectCyclicDependencies() error {
 	recStack := make(map[reflect.Type]bool)
 	for t := range graph.deps {
 		if cyclic, dep := graph.isCyclic(t, visited, recStack); cyclic {
-			return fmt.Errorf("cyclic dependency detected between %s and %s", t, dep)
+			names := typeNamesOf(t, dep)
+			return fmt.Errorf("cyclic dependency detected between %s and %s", names[0], names[1])
 		}
 	}
 
 	return nil
 }
 
+// detectCyclicDependenciesFrom uses DFS to determine if types reachable from t form a cycle. Unlike
+// detectCyclicDependencies, the search starts from t only, so the reported types don't depend on map order.
+func (graph *dependencyGraph) detectCyclicDependenciesFrom(t reflect.Type) error {
+	if cyclic, dep := graph.isCyclic(t, make(map[reflect.Type]bool), make(map[reflect.Type]bool)); cyclic {
+		names := typeNamesOf(t, dep)
+		return fmt.Errorf("cyclic dependency detected between %s and %s", names[0], names[1])
+	}
+
+	return nil
+}
+
 func (graph *dependencyGraph) isCyclic(t reflect.Type, visited, recStack map[reflect.Type]bool) (bool, reflect.Type) {
 	if recStack[t] {
 		return true, t
@@ -51,3 +92,183 @@ func (graph *dependencyGraph) isCyclic(t reflect.Type, visited, recStack map[ref
 	recStack[t] = false
 	return false, nil
 }
+
+// walk visits t and types reachable from it using DFS, each type is visited once.
+// Dependencies of a type are not visited if visit returns false for it.
+func (graph *dependencyGraph) walk(t reflect.Type, depth int, visited map[reflect.Type]bool, visit func(reflect.Type, int) bool) {
+	if visited[t] {
+		return
+	}
+
+	visited[t] = true
+	if !visit(t, depth) {
+		return
+	}
+
+	for _, dep := range graph.deps[t] {
+		if dep != nil {
+			graph.walk(dep, depth+1, visited, visit)
+		}
+	}
+}
+
+// topologicalOrder returns types reachable from roots, each one placed after all of its dependencies.
+// Roots are visited in order of their names, so the order is deterministic.
+func (graph *dependencyGraph) topologicalOrder(roots []reflect.Type) []reflect.Type {
+	visited := make(map[reflect.Type]bool)
+	order := make([]reflect.Type, 0, len(roots))
+	var visit func(t reflect.Type)
+	visit = func(t reflect.Type) {
+		if visited[t] {
+			return
+		}
+
+		visited[t] = true
+		for _, dep := range sortTypes(graph.deps[t]) {
+			if dep != nil {
+				visit(dep)
+			}
+		}
+
+		order = append(order, t)
+	}
+
+	for _, t := range sortTypes(roots) {
+		visit(t)
+	}
+
+	return order
+}
+
+// hasDependents checks if any type depends on t
+func (graph *dependencyGraph) hasDependents(t reflect.Type) bool {
+	for _, edges := range graph.edges {
+		if edges[t] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withDependents returns t along with all types that transitively depend on it
+func (graph *dependencyGraph) withDependents(t reflect.Type) []reflect.Type {
+	types := []reflect.Type{t}
+	seen := map[reflect.Type]bool{t: true}
+	for i := 0; i < len(types); i++ {
+		for from := range graph.deps {
+			if !seen[from] && graph.edges[from][types[i]] {
+				seen[from] = true
+				types = append(types, from)
+			}
+		}
+	}
+
+	return types
+}
+
+// cycleSearch holds state of DFS that collects cycles of the dependency graph
+type cycleSearch struct {
+	graph   *dependencyGraph
+	visited map[reflect.Type]bool
+	// onStack maps types of the current DFS path to their position in stack
+	onStack map[reflect.Type]int
+	stack   []reflect.Type
+	found   map[string]bool
+	cycles  []string
+}
+
+// detectAllCyclicDependencies uses DFS to find every distinct cycle closed by a back edge
+// and reports all of them in one error
+func (graph *dependencyGraph) detectAllCyclicDependencies() error {
+	search := &cycleSearch{
+		graph:   graph,
+		visited: make(map[reflect.Type]bool),
+		onStack: make(map[reflect.Type]int),
+		stack:   make([]reflect.Type, 0),
+		found:   make(map[string]bool),
+		cycles:  make([]string, 0),
+	}
+
+	// sort types to make the order of reported cycles deterministic
+	types := make([]reflect.Type, 0, len(graph.deps))
+	for t := range graph.deps {
+		types = append(types, t)
+	}
+
+	for _, t := range sortTypes(types) {
+		if !search.visited[t] {
+			search.visit(t)
+		}
+	}
+
+	if len(search.cycles) == 0 {
+		return nil
+	}
+
+	return errors.New(strings.Join(search.cycles, "\n"))
+}
+
+func (search *cycleSearch) visit(t reflect.Type) {
+	search.visited[t] = true
+	search.onStack[t] = len(search.stack)
+	search.stack = append(search.stack, t)
+
+	for _, dep := range sortTypes(search.graph.deps[t]) {
+		if dep == nil {
+			continue
+		}
+
+		if i, ok := search.onStack[dep]; ok {
+			search.addCycle(search.stack[i:])
+			continue
+		}
+
+		if !search.visited[dep] {
+			search.visit(dep)
+		}
+	}
+
+	search.stack = search.stack[:len(search.stack)-1]
+	delete(search.onStack, t)
+}
+
+// addCycle saves cycle unless it was already found starting from another type
+func (search *cycleSearch) addCycle(cycle []reflect.Type) {
+	// rotate cycle to start from the type with the least name
+	start := 0
+	for i, t := range cycle {
+		if t.String() < cycle[start].String() {
+			start = i
+		}
+	}
+
+	rotated := make([]reflect.Type, 0, len(cycle)+1)
+	for i := range cycle {
+		rotated = append(rotated, cycle[(start+i)%len(cycle)])
+	}
+
+	names := typeNamesOf(append(rotated, rotated[0])...)
+	key := strings.Join(names, " -> ")
+	if search.found[key] {
+		return
+	}
+
+	search.found[key] = true
+	search.cycles = append(search.cycles, "cyclic dependency detected: "+key)
+}
+
+// sortTypes returns a copy of types sorted by name, nil types go first
+func sortTypes(types []reflect.Type) []reflect.Type {
+	sorted := make([]reflect.Type, len(types))
+	copy(sorted, types)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i] == nil || sorted[j] == nil {
+			return sorted[i] == nil && sorted[j] != nil
+		}
+
+		return sorted[i].String() < sorted[j].String()
+	})
+
+	return sorted
+}
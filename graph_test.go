@@ -1,6 +1,7 @@
 package di
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -15,3 +16,126 @@ func TestGraph(t *testing.T) {
 	err := g.detectCyclicDependencies()
 	assert.Error(t, err)
 }
+
+func TestGraphDuplicateEdges(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func(ex *example, other *example, params exampleParams) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func() *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func() *example3 {
+		return newExample3()
+	}, Transient)
+	as.NoError(err)
+
+	as.Equal([]reflect.Type{nil, reflect.TypeOf(&example{}), reflect.TypeOf(&example3{})}, c.graph.deps[reflect.TypeOf(&example2{})])
+	as.Equal([]reflect.Type{nil}, c.graph.deps[reflect.TypeOf(&example{})])
+
+	g := newDependencyGraph()
+	g.addDependency(reflect.TypeOf(&example{}), nil)
+	g.addDependency(reflect.TypeOf(&example{}), nil)
+	g.addDependency(reflect.TypeOf(&example{}), reflect.TypeOf(&example2{}))
+	g.addDependency(reflect.TypeOf(&example{}), reflect.TypeOf(&example2{}))
+	as.Equal([]reflect.Type{nil, reflect.TypeOf(&example2{})}, g.deps[reflect.TypeOf(&example{})])
+
+	g.remove(reflect.TypeOf(&example{}))
+	g.addDependency(reflect.TypeOf(&example{}), reflect.TypeOf(&example2{}))
+	as.Equal([]reflect.Type{reflect.TypeOf(&example2{})}, g.deps[reflect.TypeOf(&example{})])
+}
+
+func TestGraphAllCycles(t *testing.T) {
+	as := assert.New(t)
+	g := newDependencyGraph()
+	g.addDependency(reflect.TypeOf(&example{}), nil)
+	g.addDependency(reflect.TypeOf(&example{}), reflect.TypeOf(&example2{}))
+	g.addDependency(reflect.TypeOf(&example2{}), reflect.TypeOf(&example{}))
+	g.addDependency(reflect.TypeOf(&example3{}), reflect.TypeOf(&example{}))
+	g.addDependency(reflect.TypeOf(&example3{}), reflect.TypeOf(&dependsOnExample{}))
+	g.addDependency(reflect.TypeOf(&dependsOnExample{}), reflect.TypeOf(&example3{}))
+	g.addDependency(reflect.TypeOf(exampleParams{}), reflect.TypeOf(exampleParams{}))
+	err := g.detectAllCyclicDependencies()
+	as.EqualError(err, "cyclic dependency detected: *di.dependsOnExample -> *di.example3 -> *di.dependsOnExample\n"+
+		"cyclic dependency detected: *di.example -> *di.example2 -> *di.example\n"+
+		"cyclic dependency detected: di.exampleParams -> di.exampleParams")
+
+	g = newDependencyGraph()
+	g.addDependency(reflect.TypeOf(&example{}), reflect.TypeOf(&example2{}))
+	g.addDependency(reflect.TypeOf(&example2{}), nil)
+	as.NoError(g.detectAllCyclicDependencies())
+}
+
+func TestGraphWalk(t *testing.T) {
+	as := assert.New(t)
+	g := newDependencyGraph()
+	g.addDependency(reflect.TypeOf(&dependsOnExample{}), nil)
+	g.addDependency(reflect.TypeOf(&dependsOnExample{}), reflect.TypeOf(&example2{}))
+	g.addDependency(reflect.TypeOf(&dependsOnExample{}), reflect.TypeOf(&example3{}))
+	g.addDependency(reflect.TypeOf(&example2{}), reflect.TypeOf(&example{}))
+	g.addDependency(reflect.TypeOf(&example{}), reflect.TypeOf(&example2{}))
+	g.addDependency(reflect.TypeOf(&example3{}), reflect.TypeOf(&example{}))
+
+	visited := make([]string, 0)
+	g.walk(reflect.TypeOf(&dependsOnExample{}), 0, make(map[reflect.Type]bool), func(t reflect.Type, depth int) bool {
+		visited = append(visited, fmt.Sprintf("%s:%d", t, depth))
+		return true
+	})
+	as.Equal([]string{"*di.dependsOnExample:0", "*di.example2:1", "*di.example:2", "*di.example3:1"}, visited)
+
+	visited = make([]string, 0)
+	g.walk(reflect.TypeOf(&dependsOnExample{}), 0, make(map[reflect.Type]bool), func(t reflect.Type, depth int) bool {
+		visited = append(visited, fmt.Sprintf("%s:%d", t, depth))
+		return t != reflect.TypeOf(&example2{})
+	})
+	as.Equal([]string{"*di.dependsOnExample:0", "*di.example2:1", "*di.example3:1", "*di.example:2"}, visited)
+}
+
+func TestGraphAcyclicCheckedOnce(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.RegisterOverridable(func() *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+	as.False(c.graph.acyclic)
+
+	err = c.Build()
+	as.NoError(err)
+	as.True(c.graph.acyclic)
+
+	// replacing a registration changes the graph, so it is checked again
+	err = c.RegisterOverridable(func(ex2 *example2) *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+	as.False(c.graph.acyclic)
+
+	err = c.Build()
+	as.Error(err)
+	as.Contains(err.Error(), "cyclic dependency detected")
+}
+
+func BenchmarkRepeatedBuild(b *testing.B) {
+	as := assert.New(b)
+	b.ReportAllocs()
+
+	c := NewContainer()
+	registerChain(as, c, Transient)
+
+	for i := 0; i < b.N; i++ {
+		_ = c.Build()
+	}
+}
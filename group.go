@@ -0,0 +1,87 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// groupTag marks the only field of types that identify members of groups
+const groupTag = "di-group"
+
+// RegisterGroup registers provider like Register does, but as a member of the group of its out-parameter type T,
+// so that several providers of T can be registered. Members of the group are injected as []T in order of registration,
+// both into providers and invokers. Such slice is registered along with the first member of the group,
+// so requesting it when no members of T were registered fails like for any unregistered type.
+func (c *Container) RegisterGroup(provider interface{}, lifetime Lifetime, opts ...RegisterOption) error {
+	outType, argTypes, constructor, err := newProviderConstructor(provider, nil)
+	if err != nil {
+		return err
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	key := groupType(outType, c.groups[outType])
+	if err := c.addConstructor(key, argTypes, constructor, lifetime, opts); err != nil {
+		return err
+	}
+
+	c.groups[outType]++
+
+	// slice of group members depends on each of them
+	sliceType := reflect.SliceOf(outType)
+	if c.constructors[sliceType] == nil || c.overridable[sliceType] {
+		c.replaceOverridable(sliceType)
+		c.lifetimes[sliceType] = Transient
+		c.constructors[sliceType] = getGroupConstructor(outType)
+	}
+
+	c.graph.addDependency(sliceType, key)
+	return nil
+}
+
+// groupType returns type that identifies index-th member of group of type t.
+// It is a struct with a single field of type t tagged with index, so that it is unique for each member.
+func groupType(t reflect.Type, index int) reflect.Type {
+	return reflect.StructOf([]reflect.StructField{{
+		Name: "Group",
+		Type: t,
+		Tag:  reflect.StructTag(fmt.Sprintf("%s:\"%d\"", groupTag, index)),
+	}})
+}
+
+// parseGroupType returns type and index of group member identified by t
+func parseGroupType(t reflect.Type) (reflect.Type, int, bool) {
+	if t == nil || t.Kind() != reflect.Struct || t.Name() != "" || t.NumField() != 1 {
+		return nil, 0, false
+	}
+
+	field := t.Field(0)
+	tag, ok := field.Tag.Lookup(groupTag)
+	if !ok {
+		return nil, 0, false
+	}
+
+	index, err := strconv.Atoi(tag)
+	return field.Type, index, err == nil
+}
+
+// getGroupConstructor returns constructor of slice of all members of group of type t
+func getGroupConstructor(t reflect.Type) innerConstructor {
+	sliceType := reflect.SliceOf(t)
+	return func(con *Container) (reflect.Value, error) {
+		n := con.groups[t]
+		s := reflect.MakeSlice(sliceType, n, n)
+		for i := 0; i < n; i++ {
+			val, err := con.resolveArg(groupType(t, i))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			s.Index(i).Set(val)
+		}
+
+		return s, nil
+	}
+}
@@ -0,0 +1,120 @@
+package di
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterGroup(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("second")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.RegisterGroup(func() exampleInterface {
+		return newExample("first")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.RegisterGroup(func(ex *example) exampleInterface {
+		return ex
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.RegisterGroup(func(ex *example) exampleInterface {
+		return newExample2(ex)
+	}, Scoped)
+	as.NoError(err)
+
+	type handler struct {
+		handlers []exampleInterface
+	}
+
+	err = c.Register(func(handlers []exampleInterface) *handler {
+		return &handler{handlers: handlers}
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(h *handler, handlers []exampleInterface) {
+		as.Len(h.handlers, 3)
+		as.Equal("first", h.handlers[0].Text())
+		as.Equal("second", h.handlers[1].Text())
+		as.IsType(&example2{}, h.handlers[2])
+		as.Len(handlers, 3)
+		// singleton member is shared
+		as.Same(h.handlers[1], handlers[1])
+		as.NotSame(h.handlers[0], handlers[0])
+	})
+	as.NoError(err)
+}
+
+func TestRegisterGroupNotRegistered(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.RegisterGroup(func(ex3 *example3) exampleInterface {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.EqualError(err, "type *di.example3 was not registered")
+
+	err = c.Register(func() *example3 {
+		return newExample3()
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	_, err = c.Get(reflect.TypeOf([]*example{}))
+	as.True(strings.HasSuffix(err.Error(), "was not registered"))
+	as.Equal("di.exampleInterface in group (#0)", typeName(groupType(reflect.TypeOf((*exampleInterface)(nil)).Elem(), 0)))
+}
+
+func TestInvokeVariadicGroup(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.RegisterGroup(func() exampleInterface {
+		return newExample("first")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.RegisterGroup(func() exampleInterface {
+		return newExample("second")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	called := false
+	err = c.Invoke(func(params ContextParams, handlers ...exampleInterface) {
+		called = true
+		as.Len(handlers, 2)
+		as.Equal("first", handlers[0].Text())
+		as.Equal("second", handlers[1].Text())
+	})
+	as.NoError(err)
+	as.True(called)
+
+	// variadic parameter is empty when no group was registered
+	called = false
+	err = c.Invoke(func(examples ...*example) {
+		called = true
+		as.Empty(examples)
+	})
+	as.NoError(err)
+	as.True(called)
+}
@@ -0,0 +1,59 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// HealthChecker is implemented by dependencies that can report whether they are healthy, e.g. connections to databases
+type HealthChecker interface {
+	// Check returns an error if the dependency is not healthy
+	Check(ctx context.Context) error
+}
+
+// HealthCheck calls Check of cached singletons that implement HealthChecker, e.g. to serve a readiness probe.
+// Singletons are checked in order of registration, an instance registered under several types is checked once.
+// Errors are joined into one, each of them is prefixed with the type of the failed singleton.
+func (c *Container) HealthCheck(ctx context.Context) error {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	checked := make(map[interface{}]bool)
+	errs := make([]string, 0)
+	for _, t := range c.registered {
+		if c.lifetimes[t] != Singleton {
+			continue
+		}
+
+		val, ok := c.singletonsCache.Get(t)
+		if !ok {
+			continue
+		}
+
+		checker, ok := val.Interface().(HealthChecker)
+		if !ok {
+			continue
+		}
+
+		if reflect.TypeOf(checker).Comparable() {
+			if checked[checker] {
+				continue
+			}
+
+			checked[checker] = true
+		}
+
+		if err := checker.Check(ctx); err != nil {
+			errs = append(errs, fmt.Sprintf("%s is not healthy: %s", typeName(t), err))
+		}
+	}
+
+	if len(errs) != 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type checker struct {
+	err    error
+	checks int
+}
+
+func (ch *checker) Check(ctx context.Context) error {
+	ch.checks++
+	return ch.err
+}
+
+type unhealthyChecker struct {
+	checker
+}
+
+type transientChecker struct {
+	checker
+}
+
+func TestHealthCheck(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	healthy := &checker{}
+	unhealthy := &unhealthyChecker{checker: checker{err: errors.New("connection refused")}}
+	transient := &transientChecker{checker: checker{err: errors.New("transient")}}
+
+	err := c.RegisterAs(func() *checker {
+		return healthy
+	}, new(HealthChecker), Singleton)
+	as.NoError(err)
+	err = c.Register(func() *unhealthyChecker {
+		return unhealthy
+	}, Singleton)
+	as.NoError(err)
+	err = c.Register(func() *example {
+		return newExample("")
+	}, Singleton)
+	as.NoError(err)
+	// only singletons are checked
+	err = c.Register(func() *transientChecker {
+		return transient
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+	err = c.Invoke(func(*transientChecker) {})
+	as.NoError(err)
+
+	err = c.HealthCheck(context.Background())
+	as.EqualError(err, "*di.unhealthyChecker is not healthy: connection refused")
+	// instance bound to an interface is checked once
+	as.Equal(1, healthy.checks)
+	as.Equal(1, unhealthy.checks)
+	as.Equal(0, transient.checks)
+
+	unhealthy.err = nil
+	as.NoError(c.HealthCheck(context.Background()))
+}
@@ -0,0 +1,73 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+type (
+	// Constructor creates a dependency, resolving its arguments from the container
+	Constructor func(*Container) (reflect.Value, error)
+
+	// Middleware wraps construction of dependencies of type t: it may act before and after calling next,
+	// replace its result or not call it at all
+	Middleware func(t reflect.Type, next Constructor) Constructor
+)
+
+var errNilMiddleware = errors.New("middleware must not be nil")
+
+// Use adds middleware that wraps construction of every dependency, so that cross-cutting concerns like
+// instrumentation or retries can be applied to all providers. Middlewares compose in order they were added:
+// the first one is the outermost. Cached values are not constructed again, so middlewares are not called for them.
+// Middleware that only applies to specific types should call next directly for the others.
+func (c *Container) Use(middleware Middleware) error {
+	if middleware == nil {
+		return errNilMiddleware
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	// derived containers must not share middlewares added after they were created
+	c.middlewares = append(c.middlewares[:len(c.middlewares):len(c.middlewares)], middleware)
+	return nil
+}
+
+// construct creates dependency of type t with its constructor wrapped by middlewares
+func (c *Container) construct(t reflect.Type, constructor innerConstructor) (val reflect.Value, err error) {
+	if c.recoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				val, err = reflect.Value{}, fmt.Errorf("provider of %s panicked: %v", typeName(t), r)
+			}
+		}()
+	}
+
+	next := Constructor(constructor)
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		next = c.middlewares[i](t, next)
+	}
+
+	start := time.Now()
+	val, err = next(c)
+	if err == nil && c.nilCheck && isNilValue(val) {
+		val, err = reflect.Value{}, fmt.Errorf("constructor for %s returned nil", typeName(t))
+	}
+
+	c.emit(Event{Kind: EventResolved, Type: t, Duration: time.Since(start), Err: err})
+	return val, err
+}
+
+// isNilValue checks if val is invalid or a nil value of a type that can be nil
+func isNilValue(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func:
+		return val.IsNil()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,95 @@
+package di
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUse(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	calls := make([]string, 0)
+	err := c.Use(func(t reflect.Type, next Constructor) Constructor {
+		return func(con *Container) (reflect.Value, error) {
+			calls = append(calls, "outer "+t.String())
+			return next(con)
+		}
+	})
+	as.NoError(err)
+
+	err = c.Use(func(t reflect.Type, next Constructor) Constructor {
+		return func(con *Container) (reflect.Value, error) {
+			calls = append(calls, "inner "+t.String())
+			return next(con)
+		}
+	})
+	as.NoError(err)
+
+	err = c.Register(func() *example {
+		return newExample("text")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+	as.Equal([]string{"outer *di.example", "inner *di.example"}, calls)
+
+	calls = calls[:0]
+	_, err = c.Get(reflect.TypeOf(&example2{}))
+	as.NoError(err)
+	// singleton is retrieved from cache without construction
+	as.Equal([]string{"outer *di.example2", "inner *di.example2"}, calls)
+}
+
+func TestUseReplacesConstruction(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	errFailed := errors.New("failed")
+	err := c.Use(func(t reflect.Type, next Constructor) Constructor {
+		if t != reflect.TypeOf(&example{}) {
+			return next
+		}
+
+		return func(con *Container) (reflect.Value, error) {
+			return reflect.Value{}, errFailed
+		}
+	})
+	as.NoError(err)
+
+	err = c.Register(func() *example {
+		return newExample("text")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func() *example3 {
+		return newExample3()
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	_, err = c.Get(reflect.TypeOf(&example3{}))
+	as.NoError(err)
+
+	_, err = c.Get(reflect.TypeOf(&example{}))
+	as.Equal(errFailed, err)
+}
+
+func TestUseNil(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Use(nil)
+	as.Equal(errNilMiddleware, err)
+}
@@ -0,0 +1,57 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Module registers a related set of providers, so that large applications can be assembled from modules
+type Module func(c *Container) error
+
+var errExternal = errors.New("dependency is provided externally")
+
+// Install registers providers of modules in container in order of modules, it stops at the first failed module
+func (c *Container) Install(modules ...Module) error {
+	for _, module := range modules {
+		if module == nil {
+			return errNilProvider
+		}
+
+		if err := module(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateModule checks module in isolation: it is installed into a new container that is built and discarded,
+// so that missing and cyclic dependencies of its providers are reported. Types provided by other modules are passed
+// as external, they are considered registered, but can't be resolved. Providers of the module are not called.
+func ValidateModule(module Module, external ...reflect.Type) error {
+	c := NewContainer(WithLazySingletons())
+	for _, t := range external {
+		if t == nil {
+			return errNilType
+		}
+
+		t := t
+		if err := c.registerConstructor(t, nil, func(*Container) (reflect.Value, error) {
+			return reflect.Value{}, fmt.Errorf("%w: %s", errExternal, typeName(t))
+		}, Transient, nil); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Install(module); err != nil {
+		return err
+	}
+
+	// instances can't be created without external dependencies, only the wiring is validated
+	for _, reg := range c.registrations {
+		reg.eagerValidate = false
+	}
+
+	return c.Build()
+}
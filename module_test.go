@@ -0,0 +1,64 @@
+package di
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstall(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	storage := func(c *Container) error {
+		return c.Register(func() *example {
+			return newExample("module")
+		}, Singleton)
+	}
+	service := func(c *Container) error {
+		return c.Register(newExample2, Transient)
+	}
+
+	as.NoError(c.Install(storage, service))
+	as.NoError(c.Build())
+	err := c.Invoke(func(ex2 *example2) {
+		as.Equal("module", ex2.Example.text)
+	})
+	as.NoError(err)
+
+	failing := func(c *Container) error {
+		return errors.New("failed")
+	}
+	as.EqualError(c.Install(failing, service), "failed")
+	as.Equal(errNilProvider, c.Install(nil))
+}
+
+func TestValidateModule(t *testing.T) {
+	as := assert.New(t)
+
+	called := false
+	module := func(c *Container) error {
+		if err := c.Register(func(ex *example) *example2 {
+			called = true
+			return newExample2(ex)
+		}, Singleton, EagerValidate()); err != nil {
+			return err
+		}
+
+		return c.Register(func(ex2 *example2) *example3 {
+			called = true
+			return newExample3()
+		}, Scoped, EagerValidate())
+	}
+
+	as.NoError(ValidateModule(module, reflect.TypeOf(&example{})))
+	as.False(called)
+
+	as.EqualError(ValidateModule(module), "type *di.example was not registered")
+	as.Equal(errNilType, ValidateModule(module, nil))
+
+	err := ValidateModule(module, reflect.TypeOf(&example2{}))
+	as.EqualError(err, "dependency *di.example2 was already registered")
+}
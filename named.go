@@ -0,0 +1,168 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// namedTag marks the only field of types that identify named dependencies
+const namedTag = "di-name"
+
+var (
+	errEmptyName = errors.New("name must not be empty")
+	stringType   = reflect.TypeOf("")
+)
+
+// RegisterNamed registers provider like Register does, but under name, so that several providers
+// of the same type can be registered. Named dependencies of type T are injected as map[string]T
+// keyed by their names. Such map is registered along with the first named dependency of type T,
+// so requesting it when no named dependencies of T were registered fails like for any unregistered type.
+func (c *Container) RegisterNamed(name string, provider interface{}, lifetime Lifetime, opts ...RegisterOption) error {
+	if name == "" {
+		return errEmptyName
+	}
+
+	outType, argTypes, constructor, err := newProviderConstructor(provider, nil)
+	if err != nil {
+		return err
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	key := namedType(outType, name)
+	if err := c.addConstructor(key, argTypes, constructor, lifetime, opts); err != nil {
+		return err
+	}
+
+	c.named[outType] = append(c.named[outType], name)
+
+	// map of named dependencies depends on each of them
+	mapType := reflect.MapOf(stringType, outType)
+	if c.constructors[mapType] == nil || c.overridable[mapType] {
+		c.replaceOverridable(mapType)
+		c.lifetimes[mapType] = Transient
+		c.constructors[mapType] = getNamedMapConstructor(outType)
+	}
+
+	c.graph.addDependency(mapType, key)
+	return nil
+}
+
+// RegisterWithNames registers provider like Register does, but resolves its parameters from named dependencies:
+// parameter i receives dependency of its type registered with RegisterNamed under names[i], parameters with empty
+// names are resolved as usual. It allows providers to accept several instances of the same type:
+//  err := c.RegisterWithNames(func(primary, replica *sql.DB) *Service {
+//		return NewService(primary, replica)
+//	}, di.Singleton, "primary", "replica")
+func (c *Container) RegisterWithNames(provider interface{}, lifetime Lifetime, names ...string) error {
+	outType, argTypes, _, err := newProviderConstructor(provider, nil)
+	if err != nil {
+		return err
+	}
+
+	if len(names) != len(argTypes) {
+		return fmt.Errorf("provider of %s has %d parameters, but %d names were given", typeName(outType), len(argTypes), len(names))
+	}
+
+	namedArgTypes := make([]reflect.Type, len(argTypes))
+	for i, argType := range argTypes {
+		namedArgTypes[i] = argType
+		if names[i] != "" {
+			namedArgTypes[i] = namedType(argType, names[i])
+		}
+	}
+
+	constructor := getConstructor(len(namedArgTypes), namedArgTypes, reflect.ValueOf(provider))
+	return c.registerConstructor(outType, namedArgTypes, constructor, lifetime, nil)
+}
+
+// namedType returns type that identifies dependency of type t registered under name.
+// It is a struct with a single field of type t tagged with name, so that it is unique for each pair of t and name.
+func namedType(t reflect.Type, name string) reflect.Type {
+	return reflect.StructOf([]reflect.StructField{{
+		Name: "Named",
+		Type: t,
+		Tag:  reflect.StructTag(fmt.Sprintf("%s:%q", namedTag, name)),
+	}})
+}
+
+// parseNamedType returns type and name of named dependency identified by t
+func parseNamedType(t reflect.Type) (reflect.Type, string, bool) {
+	if t == nil || t.Kind() != reflect.Struct || t.Name() != "" || t.NumField() != 1 {
+		return nil, "", false
+	}
+
+	field := t.Field(0)
+	name, ok := field.Tag.Lookup(namedTag)
+	return field.Type, name, ok
+}
+
+// typeName returns the name of t to be used in messages
+func typeName(t reflect.Type) string {
+	return formatTypeName(t, reflect.Type.String)
+}
+
+// typeNamesOf returns names of types to be printed in the same message. Names of different types that are
+// the same, e.g. of types declared in packages with the same name, are qualified with package paths.
+func typeNamesOf(types ...reflect.Type) []string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = typeName(t)
+	}
+
+	qualified := make([]bool, len(types))
+	for i := range types {
+		for j := i + 1; j < len(types); j++ {
+			if names[i] == names[j] && types[i] != types[j] {
+				qualified[i], qualified[j] = true, true
+			}
+		}
+	}
+
+	for i, t := range types {
+		if qualified[i] {
+			names[i] = formatTypeName(t, qualifiedName)
+		}
+	}
+
+	return names
+}
+
+// formatTypeName returns the name of t, named dependencies and members of groups are described
+// with the name of their type returned by name
+func formatTypeName(t reflect.Type, name func(reflect.Type) string) string {
+	if t == nil {
+		return fmt.Sprint(t)
+	}
+
+	if namedT, tag, ok := parseNamedType(t); ok {
+		return fmt.Sprintf("%s named %q", name(namedT), tag)
+	}
+
+	if groupT, index, ok := parseGroupType(t); ok {
+		return fmt.Sprintf("%s in group (#%d)", name(groupT), index)
+	}
+
+	return name(t)
+}
+
+// getNamedMapConstructor returns constructor of map of all named dependencies of type t
+func getNamedMapConstructor(t reflect.Type) innerConstructor {
+	mapType := reflect.MapOf(stringType, t)
+	return func(con *Container) (reflect.Value, error) {
+		names := con.named[t]
+		m := reflect.MakeMapWithSize(mapType, len(names))
+		for _, name := range names {
+			val, err := con.resolveArg(namedType(t, name))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			m.SetMapIndex(reflect.ValueOf(name), val)
+		}
+
+		return m, nil
+	}
+}
@@ -0,0 +1,224 @@
+package di
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterNamed(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.RegisterNamed("first", func() exampleInterface {
+		return newExample("first")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.RegisterNamed("second", func(ex *example) exampleInterface {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func() *example {
+		return newExample("second")
+	}, Transient)
+	as.NoError(err)
+
+	type dispatcher struct {
+		handlers map[string]exampleInterface
+	}
+
+	err = c.Register(func(handlers map[string]exampleInterface) *dispatcher {
+		return &dispatcher{handlers: handlers}
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(d *dispatcher, handlers map[string]exampleInterface) {
+		as.Len(d.handlers, 2)
+		as.Equal("first", d.handlers["first"].Text())
+		as.Equal("second", d.handlers["second"].Text())
+		as.True(d.handlers["first"] == handlers["first"])
+		as.False(d.handlers["second"] == handlers["second"])
+	})
+	as.NoError(err)
+
+	// unnamed dependency of the same type was not registered
+	err = c.Invoke(func(iface exampleInterface) {})
+	as.NotNil(err)
+	as.True(strings.HasSuffix(err.Error(), "was not registered"))
+}
+
+func TestRegisterWithNames(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	type db struct {
+		dsn string
+	}
+
+	type service struct {
+		primary, replica *db
+		ex               *example
+	}
+
+	err := c.RegisterNamed("primary", func() *db {
+		return &db{dsn: "primary"}
+	}, Singleton)
+	as.NoError(err)
+	err = c.RegisterNamed("replica", func() *db {
+		return &db{dsn: "replica"}
+	}, Singleton)
+	as.NoError(err)
+	err = c.Register(func() *example {
+		return newExample("unnamed")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.RegisterWithNames(func(primary, replica *db, ex *example) *service {
+		return &service{primary: primary, replica: replica, ex: ex}
+	}, Transient, "primary", "replica", "")
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(s *service, dbs map[string]*db) {
+		as.Equal("primary", s.primary.dsn)
+		as.Equal("replica", s.replica.dsn)
+		as.Equal("unnamed", s.ex.text)
+		as.Same(dbs["primary"], s.primary)
+	})
+	as.NoError(err)
+}
+
+func TestRegisterWithNamesErrors(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.RegisterWithNames(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.EqualError(err, "provider of *di.example2 has 1 parameters, but 0 names were given")
+
+	err = c.RegisterWithNames(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient, "missing")
+	as.NoError(err)
+
+	err = c.Build()
+	as.EqualError(err, `type *di.example named "missing" was not registered`)
+}
+
+func TestRegisterNamedErrors(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.RegisterNamed("", func() *example {
+		return newExample("")
+	}, Transient)
+	as.EqualError(err, errEmptyName.Error())
+
+	err = c.RegisterNamed("name", func() *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.RegisterNamed("name", func() *example {
+		return newExample("")
+	}, Transient)
+	as.EqualError(err, `dependency *di.example named "name" was already registered`)
+
+	err = c.Register(func() map[string]*example {
+		return nil
+	}, Transient)
+	as.NotNil(err)
+	as.True(strings.HasSuffix(err.Error(), "was already registered"))
+
+	err = c.Build()
+	as.NoError(err)
+
+	_, err = c.Get(reflect.TypeOf(map[string]*example2{}))
+	as.EqualError(err, "dependency map[string]*di.example2 was not registered")
+}
+
+func TestRegisterNamedOverridesDefaultMap(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.RegisterDefault(reflect.TypeOf(map[string]*example{}))
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(m map[string]*example) {
+		as.Len(m, 0)
+	})
+	as.NoError(err)
+
+	err = c.RegisterNamed("name", func() *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(m map[string]*example) {
+		as.Len(m, 1)
+	})
+	as.NoError(err)
+}
+
+func TestRegisterNamedCycle(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.RegisterNamed("name", func(ex2 *example2) *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(m map[string]*example) *example2 {
+		return newExample2(m["name"])
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NotNil(err)
+	as.True(strings.HasPrefix(err.Error(), "cyclic dependency detected"))
+}
+
+// foreignType is a type declared in another package with the same name as the wrapped one
+type foreignType struct {
+	reflect.Type
+}
+
+func (t foreignType) String() string {
+	return t.Type.String()
+}
+
+func (t foreignType) PkgPath() string {
+	return "example.com/other/di"
+}
+
+func TestTypeNamesOf(t *testing.T) {
+	as := assert.New(t)
+	local := reflect.TypeOf(example{})
+	foreign := foreignType{Type: local}
+
+	// types printed in the same message are qualified only if their names collide
+	as.Equal([]string{"di.example", "*di.example2"}, typeNamesOf(local, reflect.TypeOf(&example2{})))
+	as.Equal([]string{"github.com/lebedevars/di.example", "example.com/other/di.example"}, typeNamesOf(local, foreign))
+	as.Equal([]string{"di.example", "di.example"}, typeNamesOf(local, local))
+
+	// a single type is never qualified
+	as.Equal("di.example", typeName(foreign))
+	as.Equal("<nil>", typeName(nil))
+}
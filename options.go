@@ -0,0 +1,173 @@
+package di
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// Option configures container created by NewContainer
+	Option func(*Container)
+
+	// RegisterOption configures a single registration
+	RegisterOption func(*registration)
+
+	// registration holds settings of a single registration
+	registration struct {
+		trimmable            bool
+		sharedInNestedScopes bool
+		eagerValidate        bool
+		noScopeCache         bool
+		resolveTimeout       time.Duration
+		lazy                 bool
+		// lazyMu guards creation of a lazy singleton
+		lazyMu sync.Mutex
+		// acceptsRequestInfo is set for providers that depend on RequestInfo
+		acceptsRequestInfo bool
+		// init is the function provider was registered with, Reload applies it to the new provider
+		init func(interface{}) error
+		// token is set for providers registered with RegisterToken
+		token string
+	}
+)
+
+// WithSharedTransients makes Transient dependencies shared within a single Invoke, Get or ResolveAll call:
+// all dependents resolved during the call get the same instance, while separate calls get new ones.
+func WithSharedTransients() Option {
+	return func(c *Container) {
+		c.sharedTransients = true
+	}
+}
+
+// WithDefaultLifetime makes providers registered with Provide get lifetime as their lifetime instead of Transient
+func WithDefaultLifetime(lifetime Lifetime) Option {
+	return func(c *Container) {
+		c.defaultLifetime = lifetime
+	}
+}
+
+// WithLazySingletons makes Build skip creation of singletons: each of them is created and cached on its first
+// resolution instead. Errors of their providers are then returned by the first resolution instead of Build.
+func WithLazySingletons() Option {
+	return func(c *Container) {
+		c.lazySingletons = true
+	}
+}
+
+// WithPanicRecovery makes container recover from panics of providers and return them as resolution errors
+func WithPanicRecovery() Option {
+	return func(c *Container) {
+		c.recoverPanics = true
+	}
+}
+
+// WithNilCheck makes container fail resolution of dependencies whose providers return nil pointers, interfaces,
+// maps, slices, channels or functions, instead of caching and injecting nil
+func WithNilCheck() Option {
+	return func(c *Container) {
+		c.nilCheck = true
+	}
+}
+
+// WithFreezeOnBuild makes container reject registrations after Build with ErrContainerFrozen, so that goroutines
+// resolving dependencies never observe partially registered providers. Providers can still be replaced with Reload.
+func WithFreezeOnBuild() Option {
+	return func(c *Container) {
+		c.freezeOnBuild = true
+	}
+}
+
+// WithSingletonCache makes container store singletons in cache
+func WithSingletonCache(cache Cache) Option {
+	return func(c *Container) {
+		c.singletonsCache = cache
+	}
+}
+
+// WithScopedCache makes container store Scoped dependencies in caches created by newCache,
+// newCache is called once per request scope container
+func WithScopedCache(newCache func() Cache) Option {
+	return func(c *Container) {
+		c.newScopedCache = newCache
+	}
+}
+
+// WithScopePool makes containers in request scope take their caches from a pool, so that handling a request doesn't
+// allocate a new cache once a container of a previous request was returned to the pool with Release.
+// Caches set with WithScopedCache are not used by such containers.
+func WithScopePool() Option {
+	return func(c *Container) {
+		c.scopePool = &sync.Pool{New: func() interface{} {
+			return newSyncCache()
+		}}
+	}
+}
+
+// WithAllCycles makes Build report all distinct cyclic dependencies at once instead of the first one found
+func WithAllCycles() Option {
+	return func(c *Container) {
+		c.allCycles = true
+	}
+}
+
+// WithStrictScopes makes resolving Scoped dependencies from a container that is not in request scope fail
+// instead of creating an instance that is not cached, as if it was Transient. Scoped dependencies then have
+// to be resolved from containers returned by Scoped.
+func WithStrictScopes() Option {
+	return func(c *Container) {
+		c.strictScopes = true
+	}
+}
+
+// WithPointerAdaptation makes container resolve unregistered *T from registered T and vice versa.
+// Resolved values are copies: *T adapted from T points to a new copy of T, so changes made through it
+// don't affect the registered instance, and T adapted from *T is a copy of the value it points to.
+// Adapting T from nil *T fails.
+func WithPointerAdaptation() Option {
+	return func(c *Container) {
+		c.adaptPointers = true
+	}
+}
+
+// Trimmable marks a singleton as one that can be dropped from cache by TrimCache and created again on demand.
+// Singletons that depend on a trimmable one, directly or through non-singletons, must be trimmable too,
+// otherwise Build fails: they would keep using the instance closed by TrimCache.
+func Trimmable() RegisterOption {
+	return func(reg *registration) {
+		reg.trimmable = true
+	}
+}
+
+// SharedInNestedScopes makes a Scoped dependency created once per request: containers created by calling Scoped
+// on a container in request scope reuse the instance of the outermost request scope instead of creating their own
+func SharedInNestedScopes() RegisterOption {
+	return func(reg *registration) {
+		reg.sharedInNestedScopes = true
+	}
+}
+
+// NoScopeCache makes a Scoped dependency created on each resolution even in request scope, like a Transient one,
+// while it keeps other semantics of Scoped dependencies, e.g. it can't be resolved outside request scope
+// with WithStrictScopes
+func NoScopeCache() RegisterOption {
+	return func(reg *registration) {
+		reg.noScopeCache = true
+	}
+}
+
+// EagerValidate makes Build create an instance of a Scoped or Transient dependency to surface errors of its provider
+// early, as it does for singletons. The instance is discarded: it is not cached and is not resolved later.
+func EagerValidate() RegisterOption {
+	return func(reg *registration) {
+		reg.eagerValidate = true
+	}
+}
+
+// WithResolveTimeout makes resolution of a dependency fail with ErrResolveTimeout if its provider doesn't return
+// within d, e.g. when it waits for a connection from an exhausted pool. Provider is then left running
+// in the background and its result is discarded.
+func WithResolveTimeout(d time.Duration) RegisterOption {
+	return func(reg *registration) {
+		reg.resolveTimeout = d
+	}
+}
@@ -0,0 +1,741 @@
+package di
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type dependsOnExample struct {
+	Example *example
+}
+
+func registerSharedTransients(as *assert.Assertions, c *Container) {
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *dependsOnExample {
+		return &dependsOnExample{Example: ex}
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+}
+
+func TestWithSharedTransients(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithSharedTransients())
+	registerSharedTransients(as, c)
+
+	for _, con := range []*Container{c, c.Scoped()} {
+		var first *example
+		err := con.Invoke(func(ex2 *example2, dep *dependsOnExample, ex *example) {
+			as.True(ex2.Example == dep.Example)
+			as.True(ex == dep.Example)
+			first = ex
+		})
+		as.NoError(err)
+
+		err = con.Invoke(func(ex2 *example2, dep *dependsOnExample) {
+			as.True(ex2.Example == dep.Example)
+			as.False(first == ex2.Example)
+		})
+		as.NoError(err)
+
+		val, err := con.Get(reflect.TypeOf(&example{}))
+		as.NoError(err)
+		as.False(first == val.(*example))
+	}
+}
+
+func TestWithoutSharedTransients(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+	registerSharedTransients(as, c)
+
+	for _, con := range []*Container{c, c.Scoped()} {
+		var first *example
+		err := con.Invoke(func(ex2 *example2, dep *dependsOnExample, ex *example) {
+			as.False(ex2.Example == dep.Example)
+			as.False(ex == dep.Example)
+			first = ex
+		})
+		as.NoError(err)
+
+		err = con.Invoke(func(ex2 *example2) {
+			as.False(first == ex2.Example)
+		})
+		as.NoError(err)
+	}
+}
+
+type countingCache struct {
+	Cache
+	sets int
+}
+
+func (cache *countingCache) Set(t reflect.Type, val reflect.Value) {
+	cache.sets++
+	cache.Cache.Set(t, val)
+}
+
+func TestWithCaches(t *testing.T) {
+	as := assert.New(t)
+	singletons := &countingCache{Cache: newSyncCache()}
+	scoped := make([]*countingCache, 0)
+	c := NewContainer(WithSingletonCache(singletons), WithScopedCache(func() Cache {
+		cache := &countingCache{Cache: newSyncCache()}
+		scoped = append(scoped, cache)
+		return cache
+	}))
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+	as.Equal(1, singletons.sets)
+
+	for i := 0; i < 2; i++ {
+		con := c.Scoped()
+		err = con.Invoke(func(ex2 *example2) {})
+		as.NoError(err)
+		err = con.Invoke(func(ex2 *example2) {})
+		as.NoError(err)
+	}
+
+	as.Len(scoped, 2)
+	as.Equal(1, scoped[0].sets)
+	as.Equal(1, scoped[1].sets)
+}
+
+func TestWithAllCycles(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithAllCycles())
+
+	err := c.Register(func(ex2 *example2) *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(dep *dependsOnExample) *example3 {
+		return newExample3()
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(ex3 *example3) *dependsOnExample {
+		return &dependsOnExample{}
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.EqualError(err, "cyclic dependency detected: *di.dependsOnExample -> *di.example3 -> *di.dependsOnExample\n"+
+		"cyclic dependency detected: *di.example -> *di.example2 -> *di.example")
+}
+
+func TestWithStrictScopes(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithStrictScopes())
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	_, err = c.Get(reflect.TypeOf(&example{}))
+	as.EqualError(err, "scoped type *di.example resolved outside request scope")
+
+	_, err = c.Get(reflect.TypeOf(&example2{}))
+	as.EqualError(err, "failed to resolve argument 0 (*di.example) of provider of *di.example2: "+
+		"scoped type *di.example resolved outside request scope")
+
+	scoped := c.Scoped()
+	first, err := scoped.Get(reflect.TypeOf(&example{}))
+	as.NoError(err)
+
+	second, err := scoped.Get(reflect.TypeOf(&example2{}))
+	as.NoError(err)
+	as.Same(first, second.(*example2).Example)
+}
+
+type closer struct {
+	closed  int
+	err     error
+	onClose func()
+}
+
+func (cl *closer) Close() error {
+	cl.closed++
+	if cl.onClose != nil {
+		cl.onClose()
+	}
+
+	return cl.err
+}
+
+func TestTrimmable(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	closers := make([]*closer, 0)
+	err := c.Register(func() *closer {
+		cl := &closer{}
+		closers = append(closers, cl)
+		return cl
+	}, Singleton, Trimmable())
+	as.NoError(err)
+
+	err = c.Register(func() *example {
+		return newExample("")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(cl *closer) *dependsOnExample {
+		return &dependsOnExample{}
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+	as.Len(closers, 1)
+
+	ex, err := c.Get(reflect.TypeOf(&example{}))
+	as.NoError(err)
+
+	err = c.TrimCache()
+	as.NoError(err)
+	as.Equal(1, closers[0].closed)
+
+	// trimmed singleton is created again once, non-trimmable is kept
+	err = c.Invoke(func(dep *dependsOnExample, cl *closer, ex2 *example) {
+		as.True(ex2 == ex)
+	})
+	as.NoError(err)
+	as.Len(closers, 2)
+
+	_, meta, err := c.GetWithMeta(reflect.TypeOf(&closer{}))
+	as.NoError(err)
+	as.True(meta.FromCache)
+
+	closers[1].err = errors.New("close failed")
+	err = c.TrimCache()
+	as.EqualError(err, "failed to close *di.closer: close failed")
+
+	// nothing to trim
+	err = c.TrimCache()
+	as.NoError(err)
+	as.Equal(1, closers[1].closed)
+}
+
+func TestTrimmableDependents(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *closer {
+		return &closer{}
+	}, Singleton, Trimmable())
+	as.NoError(err)
+
+	err = c.Register(func(cl *closer) *dependsOnExample {
+		return &dependsOnExample{}
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(dep *dependsOnExample) *example {
+		return newExample("")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Build()
+	as.EqualError(err, "singleton *di.example depends on trimmable *di.closer, so it must be trimmable as well")
+
+	c = NewContainer()
+	err = c.Register(func() *closer {
+		return &closer{}
+	}, Singleton, Trimmable())
+	as.NoError(err)
+
+	err = c.Register(func(cl *closer) *example {
+		return newExample("")
+	}, Singleton, Trimmable())
+	as.NoError(err)
+	as.NoError(c.Build())
+
+	// trimmed dependents get the new instance
+	err = c.TrimCache()
+	as.NoError(err)
+	err = c.Invoke(func(cl *closer, ex *example) {
+		as.Equal(0, cl.closed)
+	})
+	as.NoError(err)
+}
+
+func TestTrimmableNotSingleton(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Scoped, Trimmable())
+	as.EqualError(err, "trimmable dependency *di.example must be a singleton")
+}
+
+func TestSharedInNestedScopes(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Scoped, SharedInNestedScopes())
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	get := func(con *Container) *example2 {
+		val, err := con.Get(reflect.TypeOf(&example2{}))
+		as.NoError(err)
+		return val.(*example2)
+	}
+
+	request := c.Scoped()
+	parent := get(request)
+	nested := get(request.Scoped())
+	deeplyNested := get(request.Scoped().WithContext("key", "value").Scoped())
+
+	// shared instance is reused by all scopes of the request
+	as.Same(parent.Example, nested.Example)
+	as.Same(parent.Example, deeplyNested.Example)
+	as.NotSame(parent, nested)
+	as.NotSame(nested, deeplyNested)
+
+	// another request gets its own instance
+	other := get(c.Scoped())
+	as.NotSame(parent.Example, other.Example)
+}
+
+func TestSharedInNestedScopesNotScoped(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Singleton, SharedInNestedScopes())
+	as.EqualError(err, "dependency *di.example shared in nested scopes must be scoped")
+}
+
+func TestNoScopeCache(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithStrictScopes())
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Scoped, NoScopeCache())
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Register(func() *example3 {
+		return newExample3()
+	}, Transient, NoScopeCache())
+	as.EqualError(err, "dependency *di.example3 not cached in scope must be scoped")
+
+	err = c.Build()
+	as.NoError(err)
+
+	scoped := c.Scoped()
+	err = scoped.Invoke(func(ex *example, ex2 *example2) {
+		as.NotSame(ex, ex2.Example)
+	})
+	as.NoError(err)
+
+	err = scoped.Invoke(func(ex *example, ex2 *example2) {
+		as.NotSame(ex, ex2.Example)
+	})
+	as.NoError(err)
+
+	// it is still a Scoped dependency
+	err = c.Invoke(func(ex *example) {})
+	as.EqualError(err, "failed to resolve argument 0 (*di.example) of invoker: scoped type *di.example resolved outside request scope")
+}
+
+func TestEagerValidate(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	scopedCalls := 0
+	err := c.Register(func() *example {
+		scopedCalls++
+		return newExample("")
+	}, Scoped)
+	as.NoError(err)
+
+	transientCalls := 0
+	err = c.Register(func(ex *example) *example2 {
+		transientCalls++
+		return newExample2(ex)
+	}, Transient, EagerValidate())
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+	as.Equal(1, scopedCalls)
+	as.Equal(1, transientCalls)
+
+	// validated instances are not cached
+	scoped := c.Scoped()
+	_, err = scoped.Get(reflect.TypeOf(&example2{}))
+	as.NoError(err)
+	as.Equal(2, scopedCalls)
+	as.Equal(2, transientCalls)
+
+	errInvalid := errors.New("invalid config")
+	err = c.RegisterWithInit(func() *example3 {
+		return newExample3()
+	}, Scoped, func(interface{}) error {
+		return errInvalid
+	}, EagerValidate())
+	as.NoError(err)
+
+	err = c.Build()
+	as.EqualError(err, "failed to validate *di.example3: failed to init *di.example3: invalid config")
+}
+
+type config struct {
+	name string
+}
+
+func TestWithPointerAdaptation(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithPointerAdaptation())
+
+	err := c.Register(func() config {
+		return config{name: "value"}
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func() *example {
+		return newExample("pointer")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(cfg *config, ex example) *example2 {
+		as.Equal("value", cfg.name)
+		as.Equal("pointer", ex.text)
+		return newExample2(&ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(cfg *config, ex example, ex2 *example2) {
+		as.Equal("value", cfg.name)
+		as.Equal("pointer", ex.text)
+		// adapted values are copies
+		cfg.name = "changed"
+		ex.text = "changed"
+	})
+	as.NoError(err)
+
+	err = c.Invoke(func(cfg config, ex *example) {
+		as.Equal("value", cfg.name)
+		as.Equal("pointer", ex.text)
+	})
+	as.NoError(err)
+}
+
+func TestWithPointerAdaptationNil(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithPointerAdaptation())
+
+	err := c.Register(func() *example {
+		return nil
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	_, err = c.Get(reflect.TypeOf(example{}))
+	as.EqualError(err, "can't adapt nil *di.example to di.example")
+}
+
+func TestWithoutPointerAdaptation(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() config {
+		return config{}
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(cfg *config) *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.EqualError(err, "type *di.config was not registered")
+}
+
+func TestWithPointerAdaptationCycle(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithPointerAdaptation())
+
+	err := c.Register(func(cfg *config) config {
+		return *cfg
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.Error(err)
+	as.Contains(err.Error(), "cyclic dependency detected")
+}
+
+func TestWithDefaultLifetime(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithDefaultLifetime(Scoped))
+
+	err := c.Provide(func() *example {
+		return newExample("")
+	})
+	as.NoError(err)
+
+	as.Equal(map[reflect.Type]Lifetime{reflect.TypeOf(&example{}): Scoped}, c.Lifetimes())
+}
+
+func TestWithLazySingletons(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithLazySingletons())
+
+	calls := 0
+	err := c.Register(func() *example {
+		calls++
+		return newExample("")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+	as.Equal(0, calls)
+
+	var first *example
+	err = c.Scoped().Invoke(func(ex2 *example2) {
+		first = ex2.Example
+	})
+	as.NoError(err)
+	as.Equal(1, calls)
+
+	err = c.Invoke(func(ex *example) {
+		as.Same(first, ex)
+	})
+	as.NoError(err)
+	as.Equal(1, calls)
+}
+
+func TestWithPanicRecovery(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithPanicRecovery())
+
+	err := c.Register(func() *example {
+		panic("no config")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(ex2 *example2) {})
+	as.EqualError(err, "failed to resolve argument 0 (*di.example2) of invoker: "+
+		"failed to resolve argument 0 (*di.example) of provider of *di.example2: provider of *di.example panicked: no config")
+
+	c = NewContainer()
+	err = c.Register(func() *example {
+		panic("no config")
+	}, Singleton)
+	as.NoError(err)
+
+	as.Panics(func() {
+		_ = c.Build()
+	})
+}
+
+func TestWithNilCheck(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithNilCheck())
+
+	err := c.Register(func() *example {
+		return nil
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func() exampleInterface {
+		return nil
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func() []int {
+		return []int{}
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(ex *example) {})
+	as.EqualError(err, "failed to resolve argument 0 (*di.example) of invoker: constructor for *di.example returned nil")
+
+	_, err = c.Get(reflect.TypeOf((*exampleInterface)(nil)).Elem())
+	as.EqualError(err, "constructor for di.exampleInterface returned nil")
+
+	err = c.Invoke(func(ints []int) {
+		as.Empty(ints)
+	})
+	as.NoError(err)
+
+	// nil is injected by default
+	c = NewContainer()
+	err = c.Register(func() *example {
+		return nil
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(ex *example) {
+		as.Nil(ex)
+	})
+	as.NoError(err)
+}
+
+func TestWithFreezeOnBuild(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithFreezeOnBuild())
+
+	err := c.Register(func() *example {
+		return newExample("old")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Register(func() *example3 {
+		return newExample3()
+	}, Transient)
+	as.Equal(ErrContainerFrozen, err)
+
+	err = c.RegisterDefault(reflect.TypeOf(&config{}))
+	as.Equal(ErrContainerFrozen, err)
+
+	err = c.Scoped().Register(func() *example3 {
+		return newExample3()
+	}, Transient)
+	as.Equal(ErrContainerFrozen, err)
+
+	err = c.Reload(reflect.TypeOf(&example{}), func() *example {
+		return newExample("new")
+	})
+	as.NoError(err)
+
+	err = c.Invoke(func(ex *example) {
+		as.Equal("new", ex.text)
+	})
+	as.NoError(err)
+
+	// containers are not frozen by default
+	c = NewContainer()
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Register(func() *example3 {
+		return newExample3()
+	}, Transient)
+	as.NoError(err)
+}
+
+func TestWithResolveTimeout(t *testing.T) {
+	as := assert.New(t)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	c := NewContainer(WithPanicRecovery())
+	err := c.Register(func() *example {
+		<-release
+		return &example{}
+	}, Transient, WithResolveTimeout(10*time.Millisecond))
+	as.NoError(err)
+	err = c.Register(newExample3, Transient, WithResolveTimeout(time.Second))
+	as.NoError(err)
+	err = c.Register(func() *example2 {
+		panic("boom")
+	}, Transient, WithResolveTimeout(time.Second))
+	as.NoError(err)
+	as.NoError(c.Build())
+
+	err = c.Invoke(func(ex *example) {})
+	as.True(errors.Is(err, ErrResolveTimeout))
+	as.Contains(err.Error(), "*di.example after 10ms")
+
+	ex3, err := c.Get(reflect.TypeOf(&example3{}))
+	as.NoError(err)
+	as.NotNil(ex3)
+
+	_, err = c.Get(reflect.TypeOf(&example2{}))
+	as.EqualError(err, "provider of *di.example2 panicked: boom")
+
+	err = c.Register(newExample3, Transient, WithResolveTimeout(-time.Second))
+	as.EqualError(err, "resolve timeout of *di.example3 must not be negative")
+}
@@ -0,0 +1,228 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type (
+	// In marks a struct as a parameter object when embedded into it.
+	// Instead of being resolved as a dependency itself, a parameter object is assembled by the container:
+	// each of its exported fields is resolved independently. Fields tagged `di:"-"` are left zero.
+	// Fields tagged `di:"ctx:key"` are set to container's context value by key, or left zero if there is no such value.
+	//  type params struct {
+	//		di.In
+	//		Repo      *Repository
+	//		Logger    *Logger
+	//		RequestID string `di:"ctx:requestID"`
+	//	}
+	In struct{}
+
+	// Out marks a struct as a result object when embedded into it.
+	// Provider returning a result object registers each of its exported fields as a separate dependency
+	// with provider's lifetime. Fields tagged `di:"-"` are not registered.
+	//  type results struct {
+	//		di.Out
+	//		DB    *sql.DB
+	//		Cache *Cache
+	//	}
+	Out struct{}
+)
+
+const (
+	tagName   = "di"
+	tagInject = "inject"
+	tagSkip   = "-"
+	// tagContext prefixes key of context value that is set to the field
+	tagContext = "ctx:"
+)
+
+var (
+	inMarkerType  = reflect.TypeOf(In{})
+	outMarkerType = reflect.TypeOf(Out{})
+)
+
+// isParamObject checks if t is a struct that either embeds In or has at least one field tagged `di:"inject"`
+// or bound to context value
+func isParamObject(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type == inMarkerType {
+			return true
+		}
+
+		if field.Tag.Get(tagName) == tagInject {
+			return true
+		}
+
+		if _, ok := contextKey(field); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// contextKey returns key of context value bound to field by `di:"ctx:key"` tag
+func contextKey(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get(tagName)
+	if !strings.HasPrefix(tag, tagContext) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(tag, tagContext), true
+}
+
+// isResultObject checks if t is a struct that embeds Out
+func isResultObject(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.Anonymous && field.Type == outMarkerType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resultFields returns exported fields of result object t that are registered as dependencies
+func resultFields(t reflect.Type) []reflect.StructField {
+	fields := make([]reflect.StructField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Type == outMarkerType || field.Tag.Get(tagName) == tagSkip {
+			continue
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+// getFieldConstructor returns constructor that takes the field with index from resolved result object
+func getFieldConstructor(resultType reflect.Type, index []int) innerConstructor {
+	return func(con *Container) (reflect.Value, error) {
+		result, err := con.resolveArg(resultType)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		return result.FieldByIndex(index), nil
+	}
+}
+
+// injectedFields returns fields of parameter object t that are resolved by the container:
+// all exported fields of structs embedding In and only fields tagged `di:"inject"` otherwise.
+// Fields bound to context values are always returned.
+// Untagged and unexported fields are left zero.
+func injectedFields(t reflect.Type) []reflect.StructField {
+	embedsIn := false
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.Anonymous && field.Type == inMarkerType {
+			embedsIn = true
+			break
+		}
+	}
+
+	fields := make([]reflect.StructField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		// skip unexported fields and the marker itself
+		if field.PkgPath != "" || field.Type == inMarkerType {
+			continue
+		}
+
+		tag := field.Tag.Get(tagName)
+		_, fromContext := contextKey(field)
+		if tag == tagInject || fromContext || (embedsIn && tag != tagSkip) {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+// dependenciesOf returns types that need to be registered for argType to be resolved:
+// none for ContextParams and context.Context, fields of parameter objects (nested parameter objects are flattened)
+// and argType itself otherwise
+func dependenciesOf(argType reflect.Type) []reflect.Type {
+	if argType == contextParamsType || argType == contextType || argType == requestInfoType {
+		return nil
+	}
+
+	if !isParamObject(argType) {
+		return []reflect.Type{argType}
+	}
+
+	deps := make([]reflect.Type, 0)
+	for _, field := range injectedFields(argType) {
+		// context values are not registered
+		if _, ok := contextKey(field); ok {
+			continue
+		}
+
+		deps = append(deps, dependenciesOf(field.Type)...)
+	}
+
+	return deps
+}
+
+// newParamObject assembles parameter object of type t, resolving its fields with resolve
+func (c *Container) newParamObject(t reflect.Type, resolve func(reflect.Type) (reflect.Value, error)) (reflect.Value, error) {
+	obj := reflect.New(t).Elem()
+	for _, field := range injectedFields(t) {
+		var (
+			val reflect.Value
+			err error
+		)
+
+		key, fromContext := contextKey(field)
+		switch {
+		case fromContext:
+			val, err = c.contextValue(key, field)
+			// missing values leave the field zero
+			if err == nil && !val.IsValid() {
+				continue
+			}
+		case field.Type == contextParamsType || field.Type == contextType || field.Type == requestInfoType:
+			val, _ = c.contextArg(field.Type)
+		case isParamObject(field.Type):
+			val, err = c.newParamObject(field.Type, resolve)
+		default:
+			val, err = resolve(field.Type)
+		}
+
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		obj.FieldByIndex(field.Index).Set(val)
+	}
+
+	return obj, nil
+}
+
+// contextValue returns context value by key to be set to field, invalid value is returned if there is no such value
+func (c *Container) contextValue(key string, field reflect.StructField) (reflect.Value, error) {
+	value, ok := c.contextParams[key]
+	if !ok || value == nil {
+		return reflect.Value{}, nil
+	}
+
+	val := reflect.ValueOf(value)
+	if !val.Type().AssignableTo(field.Type) {
+		return reflect.Value{}, fmt.Errorf("context value %q of type %s can't be set to field %s of type %s",
+			key, typeName(val.Type()), field.Name, typeName(field.Type))
+	}
+
+	return val, nil
+}
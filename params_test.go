@@ -0,0 +1,263 @@
+package di
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type exampleParams struct {
+	In
+	Example  *example
+	Example3 *example3
+	Skipped  *example3 `di:"-"`
+}
+
+type taggedParams struct {
+	Example  *example `di:"inject"`
+	Untagged *example3
+}
+
+type nestedParams struct {
+	In
+	Params  exampleParams
+	Context ContextParams
+}
+
+func TestParamObject(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("injected")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func() *example3 {
+		return newExample3()
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(params exampleParams) *example2 {
+		as.NotNil(params.Example3)
+		as.Nil(params.Skipped)
+		return newExample2(params.Example)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(ex2 *example2) {
+		as.Equal("injected", ex2.Example.text)
+	})
+	as.NoError(err)
+}
+
+func TestParamObjectTagged(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("injected")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(params taggedParams) *example2 {
+		as.Nil(params.Untagged)
+		return newExample2(params.Example)
+	}, Transient)
+	as.NoError(err)
+
+	// *example3 is not required as the field is not tagged
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(ex2 *example2) {
+		as.Equal("injected", ex2.Example.text)
+	})
+	as.NoError(err)
+}
+
+func TestParamObjectContext(t *testing.T) {
+	type contextParams struct {
+		Text  string           `di:"ctx:text"`
+		Iface exampleInterface `di:"ctx:iface"`
+		Count int              `di:"ctx:count"`
+	}
+
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func(params contextParams) *example {
+		return newExample(params.Text)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.WithContext("text", "context").WithContext("iface", newExample("")).Invoke(func(ex *example, params contextParams) {
+		as.Equal("context", ex.text)
+		as.NotNil(params.Iface)
+		// missing values are left zero
+		as.Equal(0, params.Count)
+	})
+	as.NoError(err)
+
+	err = c.WithContext("count", "one").Invoke(func(params contextParams) {})
+	as.EqualError(err, `failed to resolve argument 0 (di.contextParams) of invoker: `+
+		`context value "count" of type string can't be set to field Count of type int`)
+}
+
+func TestParamObjectNested(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("injected")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func() *example3 {
+		return newExample3()
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(params nestedParams) *example2 {
+		as.NotNil(params.Params.Example3)
+		as.Equal("value", params.Context.GetValue("key"))
+		return newExample2(params.Params.Example)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.WithContext("key", "value").Invoke(func(ex2 *example2) {
+		as.Equal("injected", ex2.Example.text)
+	})
+	as.NoError(err)
+}
+
+func TestParamObjectInvoke(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("injected")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	err = c.Invoke(func(params taggedParams) {
+		as.Equal("injected", params.Example.text)
+		as.Nil(params.Untagged)
+	})
+	as.NoError(err)
+
+	err = c.Invoke(func(params exampleParams) {})
+	as.NotNil(err)
+	as.True(strings.HasSuffix(err.Error(), "was not registered"))
+}
+
+func TestParamObjectUnregisteredField(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(params exampleParams) *example2 {
+		return newExample2(params.Example)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NotNil(err)
+	as.True(strings.HasSuffix(err.Error(), "was not registered"))
+}
+
+type exampleResults struct {
+	Out
+	Example  *example
+	Example3 *example3
+	Skipped  string `di:"-"`
+}
+
+func TestResultObject(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	constructed := 0
+	err := c.Register(func() exampleResults {
+		constructed++
+		return exampleResults{Example: newExample("injected"), Example3: newExample3()}
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+	as.Equal(1, constructed)
+
+	err = c.Invoke(func(ex *example, ex2 *example2, ex3 *example3, results exampleResults) {
+		as.Equal("injected", ex.text)
+		as.Equal(ex, ex2.Example)
+		as.Equal(results.Example, ex)
+		as.Equal(results.Example3, ex3)
+	})
+	as.NoError(err)
+	as.Equal(1, constructed)
+
+	err = c.Invoke(func(s string) {})
+	as.NotNil(err)
+	as.True(strings.HasSuffix(err.Error(), "was not registered"))
+}
+
+func TestResultObjectScoped(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	constructed := 0
+	err := c.Register(func() exampleResults {
+		constructed++
+		return exampleResults{Example: newExample("injected"), Example3: newExample3()}
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	c = c.Scoped()
+	err = c.Invoke(func(ex *example) {})
+	as.NoError(err)
+	err = c.Invoke(func(ex3 *example3) {})
+	as.NoError(err)
+	as.Equal(1, constructed)
+}
+
+func TestResultObjectDoubleRegister(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func() exampleResults {
+		return exampleResults{}
+	}, Transient)
+	as.NotNil(err)
+	as.True(strings.HasSuffix(err.Error(), "was already registered"))
+}
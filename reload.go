@@ -0,0 +1,167 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Reload replaces provider of registered type t and evicts cached instances of t and of all types that transitively
+// depend on it, so that they are created again with the new provider, e.g. to apply new configuration without
+// rebuilding the container. Lifetime, options and init function of the registration are kept. Evicted singletons of
+// a built container are created again right away, while Scoped dependencies are only evicted from cache of c:
+// other containers in request scope keep their instances. If any of the singletons fails to be created,
+// the old provider and cached instances are kept.
+func (c *Container) Reload(t reflect.Type, provider interface{}) error {
+	if t == nil {
+		return errNilType
+	}
+
+	outType, argTypes, constructor, err := newProviderConstructor(provider, nil)
+	if err != nil {
+		return err
+	}
+
+	if outType != t {
+		return fmt.Errorf("provider of %s can't reload %s", typeName(outType), typeName(t))
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	oldConstructor := c.constructors[t]
+	if oldConstructor == nil {
+		return fmt.Errorf("dependency %s was not registered", typeName(t))
+	}
+
+	// container is not built again, so new dependencies must already be registered
+	for _, argType := range argTypes {
+		for _, dep := range dependenciesOf(argType) {
+			if _, ok := c.adaptedType(dep); c.constructors[dep] == nil && !ok {
+				return &NotRegisteredError{Type: dep}
+			}
+		}
+	}
+
+	if reg, ok := c.registrations[t]; ok {
+		if reg.init != nil {
+			constructor = withInit(constructor, reg.init)
+		}
+
+		if reg.resolveTimeout > 0 {
+			constructor = withTimeout(t, constructor, reg.resolveTimeout)
+		}
+	}
+
+	oldDeps := c.graph.deps[t]
+	restoreDeps := func() {
+		c.graph.remove(t)
+		for _, dep := range oldDeps {
+			c.graph.addDependency(t, dep)
+		}
+	}
+
+	c.setDependencies(t, argTypes)
+	// the rest of the graph was acyclic, so a new cycle must pass through t
+	if err := c.graph.detectCyclicDependenciesFrom(t); err != nil {
+		restoreDeps()
+		return err
+	}
+
+	evicted := c.graph.withDependents(t)
+	created, err := c.recreateSingletons(t, constructor, evicted)
+	if err != nil {
+		restoreDeps()
+		return err
+	}
+
+	c.constructorsM.Lock()
+	c.constructors[t] = constructor
+	c.constructorsM.Unlock()
+	for _, dep := range evicted {
+		switch c.lifetimes[dep] {
+		case Singleton:
+			if val, ok := created.Get(dep); ok {
+				c.singletonsCache.Set(dep, val)
+			} else {
+				c.singletonsCache.Delete(dep)
+			}
+		case Scoped:
+			if c.scope == RequestScope {
+				c.scopedCacheOf(dep).Delete(dep)
+			}
+		}
+	}
+
+	return nil
+}
+
+// recreateSingletons creates evicted singletons of a built container with constructor of t, like Build does.
+// They are cached apart from singletons of c, so that c is not changed if any of them fails to be created.
+func (c *Container) recreateSingletons(t reflect.Type, constructor innerConstructor, evicted []reflect.Type) (Cache, error) {
+	created := newSyncCache()
+	if !c.built || c.lazySingletons {
+		return created, nil
+	}
+
+	// singletons can only be created by a container that is not built
+	con := c.derive()
+	con.built = false
+	con.constructors = make(map[reflect.Type]innerConstructor, len(c.constructors))
+	for dep, depConstructor := range c.constructors {
+		con.constructors[dep] = depConstructor
+	}
+
+	con.constructors[t] = constructor
+	cache := &reloadCache{Cache: c.singletonsCache, evicted: make(map[reflect.Type]bool, len(evicted)), created: created}
+	for _, dep := range evicted {
+		cache.evicted[dep] = true
+	}
+
+	con.singletonsCache = cache
+
+	for _, dep := range c.graph.topologicalOrder(evicted) {
+		if c.lifetimes[dep] == Singleton && !c.isLazy(dep) {
+			if _, err := con.resolveArg(dep); err != nil {
+				return nil, fmt.Errorf("failed to build singleton %s: %w", typeName(dep), err)
+			}
+		}
+	}
+
+	return created, nil
+}
+
+// reloadCache reads singletons that are not evicted by Reload from the embedded cache,
+// while evicted ones are read from and stored to created
+type reloadCache struct {
+	Cache
+	evicted map[reflect.Type]bool
+	created Cache
+}
+
+func (cache *reloadCache) Get(t reflect.Type) (reflect.Value, bool) {
+	if cache.evicted[t] {
+		return cache.created.Get(t)
+	}
+
+	return cache.Cache.Get(t)
+}
+
+func (cache *reloadCache) Set(t reflect.Type, val reflect.Value) {
+	cache.created.Set(t, val)
+}
+
+func (cache *reloadCache) Delete(t reflect.Type) {
+	cache.created.Delete(t)
+}
+
+// setDependencies replaces dependencies of t in the dependency graph with the ones of argTypes,
+// which must be registered
+func (c *Container) setDependencies(t reflect.Type, argTypes []reflect.Type) {
+	c.graph.remove(t)
+	c.graph.addDependency(t, nil)
+	for _, argType := range argTypes {
+		for _, dep := range dependenciesOf(argType) {
+			c.graph.addDependency(t, dep)
+		}
+	}
+}
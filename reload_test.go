@@ -0,0 +1,209 @@
+package di
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReload(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example3 {
+		return newExample3()
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ex3 *example3) *example {
+		return newExample("old")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Register(func(ex3 *example3) *config {
+		return &config{}
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	scoped := c.Scoped()
+	var oldEx3 *example3
+	var oldEx2 *example2
+	var oldCfg *config
+	err = scoped.Invoke(func(ex3 *example3, ex2 *example2, cfg *config) {
+		oldEx3, oldEx2, oldCfg = ex3, ex2, cfg
+	})
+	as.NoError(err)
+
+	err = scoped.Reload(reflect.TypeOf(&example{}), func(cfg *config) *example {
+		return newExample("new")
+	})
+	as.NoError(err)
+
+	// only the reloaded type and its dependents are evicted
+	err = scoped.Invoke(func(ex3 *example3, ex *example, ex2 *example2, cfg *config) {
+		as.Same(oldEx3, ex3)
+		as.Same(oldCfg, cfg)
+		as.Equal("new", ex.text)
+		as.NotSame(oldEx2, ex2)
+		as.Same(ex, ex2.Example)
+	})
+	as.NoError(err)
+	as.Equal([]reflect.Type{reflect.TypeOf(&config{})}, c.Dependencies(reflect.TypeOf(&example{})))
+
+	err = c.Reload(reflect.TypeOf(&example{}), func(ex2 *example2) *example {
+		return newExample("")
+	})
+	as.EqualError(err, "cyclic dependency detected between *di.example and *di.example2")
+	as.Equal([]reflect.Type{reflect.TypeOf(&config{})}, c.Dependencies(reflect.TypeOf(&example{})))
+
+	err = c.Reload(reflect.TypeOf(&example{}), func(ex2 *dependsOnExample) *example {
+		return newExample("")
+	})
+	as.EqualError(err, "dependency *di.dependsOnExample was not registered")
+
+	err = c.Reload(reflect.TypeOf(&example{}), func() *example2 {
+		return nil
+	})
+	as.EqualError(err, "provider of *di.example2 can't reload *di.example")
+
+	err = c.Reload(reflect.TypeOf(&closer{}), func() *closer {
+		return nil
+	})
+	as.EqualError(err, "dependency *di.closer was not registered")
+}
+
+func TestReloadKeepsRegistration(t *testing.T) {
+	as := assert.New(t)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	c := NewContainer()
+	err := c.RegisterWithInit(func() *example {
+		return newExample("old")
+	}, Transient, func(val interface{}) error {
+		val.(*example).text += " initialized"
+		return nil
+	})
+	as.NoError(err)
+	err = c.Register(newExample3, Transient, WithResolveTimeout(10*time.Millisecond))
+	as.NoError(err)
+	as.NoError(c.Build())
+
+	err = c.Reload(reflect.TypeOf(&example{}), func() *example {
+		return newExample("new")
+	})
+	as.NoError(err)
+
+	err = c.Reload(reflect.TypeOf(&example3{}), func() *example3 {
+		<-release
+		return newExample3()
+	})
+	as.NoError(err)
+
+	err = c.Invoke(func(ex *example) {
+		as.Equal("new initialized", ex.text)
+	})
+	as.NoError(err)
+
+	_, err = c.Get(reflect.TypeOf(&example3{}))
+	as.True(errors.Is(err, ErrResolveTimeout))
+}
+
+func TestReloadFailure(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("old")
+	}, Singleton)
+	as.NoError(err)
+	err = c.RegisterWithInit(newExample2, Singleton, func(val interface{}) error {
+		if val.(*example2).Text() == "bad" {
+			return errors.New("bad example")
+		}
+
+		return nil
+	})
+	as.NoError(err)
+	err = c.Register(newExample3, Singleton)
+	as.NoError(err)
+	as.NoError(c.Build())
+
+	var oldEx *example
+	var oldEx2 *example2
+	err = c.Invoke(func(ex *example, ex2 *example2) {
+		oldEx, oldEx2 = ex, ex2
+	})
+	as.NoError(err)
+
+	// failure of a dependent keeps the old provider, its dependencies and cached instances
+	err = c.Reload(reflect.TypeOf(&example{}), func(ex3 *example3) *example {
+		return newExample("bad")
+	})
+	as.EqualError(err, "failed to build singleton *di.example2: failed to init *di.example2: bad example")
+	as.Empty(c.Dependencies(reflect.TypeOf(&example{})))
+
+	err = c.Invoke(func(ex *example, ex2 *example2) {
+		as.Same(oldEx, ex)
+		as.Same(oldEx2, ex2)
+	})
+	as.NoError(err)
+
+	err = c.Reload(reflect.TypeOf(&example{}), func() *example {
+		return newExample("new")
+	})
+	as.NoError(err)
+
+	err = c.Invoke(func(ex *example, ex2 *example2) {
+		as.Equal("new", ex.text)
+		as.Same(ex, ex2.Example)
+	})
+	as.NoError(err)
+}
+
+func TestReloadConcurrent(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer(WithFreezeOnBuild())
+
+	err := c.Register(func() *example {
+		return newExample("0")
+	}, Singleton)
+	as.NoError(err)
+	err = c.Register(newExample2, Transient)
+	as.NoError(err)
+	as.NoError(c.Build())
+
+	// providers are replaced while other goroutines resolve dependencies
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			as.NoError(c.Reload(reflect.TypeOf(&example{}), func() *example {
+				return newExample("reloaded")
+			}))
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		_, err := c.Get(reflect.TypeOf(&example2{}))
+		as.NoError(err)
+	}
+
+	<-done
+	err = c.Invoke(func(ex2 *example2) {
+		as.Equal("reloaded", ex2.Text())
+	})
+	as.NoError(err)
+}
@@ -0,0 +1,65 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	errNilSelector  = errors.New("selector must not be nil")
+	reflectTypeType = reflect.TypeOf((*reflect.Type)(nil)).Elem()
+)
+
+// RegisterSelector registers interface pointed to by iface, e.g. (*Repository)(nil), to be resolved as one of the
+// registered types that implement it. The type is chosen at resolution time by selector based on container's context:
+// it returns either reflect.Type or a value of the chosen type, e.g. (*PostgresRepository)(nil).
+// Resolution fails if selector returns nil, a type that does not implement the interface or a type that was not registered.
+// The resolved value is cached according to lifetime, so selector is called once per scope for Scoped dependencies.
+func (c *Container) RegisterSelector(iface interface{}, selector func(ContextParams) interface{}, lifetime Lifetime) error {
+	ifaceType, err := interfaceOf(iface)
+	if err != nil {
+		return err
+	}
+
+	if selector == nil {
+		return errNilSelector
+	}
+
+	return c.registerConstructor(ifaceType, nil, func(con *Container) (reflect.Value, error) {
+		t, err := selectedType(ifaceType, selector(con.contextParams))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		val, err := con.resolveArg(t)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("failed to resolve %s selected for %s: %w", typeName(t), typeName(ifaceType), err)
+		}
+
+		return val, nil
+	}, lifetime, nil)
+}
+
+// selectedType returns type identified by key returned by selector of ifaceType
+func selectedType(ifaceType reflect.Type, key interface{}) (reflect.Type, error) {
+	if key == nil {
+		return nil, fmt.Errorf("selector of %s returned nil", typeName(ifaceType))
+	}
+
+	t := reflect.TypeOf(key)
+	if t.Implements(reflectTypeType) {
+		t = key.(reflect.Type)
+	}
+
+	// resolving the interface itself would call selector again
+	if t == ifaceType {
+		return nil, fmt.Errorf("selector of %s returned the interface itself", typeName(ifaceType))
+	}
+
+	if !t.Implements(ifaceType) {
+		return nil, fmt.Errorf("type %s selected for %s does not implement it", typeName(t), typeName(ifaceType))
+	}
+
+	return t, nil
+}
@@ -0,0 +1,98 @@
+package di
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterSelector(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("example")
+	}, Transient)
+	as.NoError(err)
+
+	err = c.Register(func(ex *example) *example2 {
+		return newExample2(ex)
+	}, Transient)
+	as.NoError(err)
+
+	err = c.RegisterSelector((*exampleInterface)(nil), func(params ContextParams) interface{} {
+		switch params.GetValue("impl") {
+		case "example":
+			return (*example)(nil)
+		case "example2":
+			return reflect.TypeOf(&example2{})
+		case "example3":
+			return &example3{}
+		case "interface":
+			return reflect.TypeOf((*exampleInterface)(nil)).Elem()
+		case "unregistered":
+			return &unregisteredExample{}
+		default:
+			return nil
+		}
+	}, Scoped)
+	as.NoError(err)
+
+	err = c.Build()
+	as.NoError(err)
+
+	ifaceType := reflect.TypeOf((*exampleInterface)(nil)).Elem()
+	val, err := c.WithContext("impl", "example").Get(ifaceType)
+	as.NoError(err)
+	as.IsType(&example{}, val)
+
+	val, err = c.WithContext("impl", "example2").Get(ifaceType)
+	as.NoError(err)
+	as.IsType(&example2{}, val)
+
+	// selected value is cached in request scope
+	scoped := c.Scoped().WithContext("impl", "example")
+	first, err := scoped.Get(ifaceType)
+	as.NoError(err)
+	second, err := scoped.Get(ifaceType)
+	as.NoError(err)
+	as.Same(first, second)
+
+	_, err = c.Get(ifaceType)
+	as.EqualError(err, "selector of di.exampleInterface returned nil")
+
+	_, err = c.WithContext("impl", "example3").Get(ifaceType)
+	as.EqualError(err, "type *di.example3 selected for di.exampleInterface does not implement it")
+
+	_, err = c.WithContext("impl", "interface").Get(ifaceType)
+	as.EqualError(err, "selector of di.exampleInterface returned the interface itself")
+
+	_, err = c.WithContext("impl", "unregistered").Get(ifaceType)
+	as.EqualError(err, "failed to resolve *di.unregisteredExample selected for di.exampleInterface: "+
+		"dependency *di.unregisteredExample was not registered")
+}
+
+type unregisteredExample struct{}
+
+func (u *unregisteredExample) Text() string {
+	return ""
+}
+
+func TestRegisterSelectorErrors(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	selector := func(ContextParams) interface{} {
+		return (*example)(nil)
+	}
+
+	err := c.RegisterSelector(nil, selector, Transient)
+	as.Equal(errNilType, err)
+
+	err = c.RegisterSelector(&example{}, selector, Transient)
+	as.Equal(errNotInterface, err)
+
+	err = c.RegisterSelector((*exampleInterface)(nil), nil, Transient)
+	as.Equal(errNilSelector, err)
+}
@@ -0,0 +1,37 @@
+package di
+
+import "fmt"
+
+// RegisterToken registers provider like Register does and maps token to the type it provides, so that
+// the dependency can be resolved with GetByToken, e.g. when implementations are selected by names from configuration.
+// Each token can only be registered once, registering it again fails even if the provider is of another type.
+func (c *Container) RegisterToken(token string, provider interface{}, lifetime Lifetime, opts ...RegisterOption) error {
+	if token == "" {
+		return errEmptyName
+	}
+
+	return c.register(provider, lifetime, nil, append(opts[:len(opts):len(opts)], func(reg *registration) {
+		reg.token = token
+	}))
+}
+
+// checkToken checks that token passed to RegisterToken was not registered yet, empty token is not checked
+func (c *Container) checkToken(token string) error {
+	if t, ok := c.tokens[token]; ok && token != "" {
+		return fmt.Errorf("token %q was already registered for %s", token, typeName(t))
+	}
+
+	return nil
+}
+
+// GetByToken returns dependency registered with RegisterToken under token
+func (c *Container) GetByToken(token string) (interface{}, error) {
+	c.m.RLock()
+	t, ok := c.tokens[token]
+	c.m.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("token %q was not registered", token)
+	}
+
+	return c.Get(t)
+}
@@ -0,0 +1,76 @@
+package di
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterToken(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.RegisterToken("example", func() *example {
+		return newExample("token")
+	}, Singleton)
+	as.NoError(err)
+	err = c.RegisterToken("example2", newExample2, Transient)
+	as.NoError(err)
+
+	err = c.RegisterToken("example", newExample3, Transient)
+	as.EqualError(err, `token "example" was already registered for *di.example`)
+	err = c.RegisterToken("", newExample3, Transient)
+	as.Equal(errEmptyName, err)
+	err = c.RegisterToken("other", func() *example {
+		return newExample("other")
+	}, Singleton)
+	as.EqualError(err, "dependency *di.example was already registered")
+
+	err = c.Build()
+	as.NoError(err)
+
+	val, err := c.Scoped().WithContext("key", "value").GetByToken("example2")
+	as.NoError(err)
+	as.Equal("token", val.(*example2).Example.text)
+
+	// tokenized dependencies are resolved by their types as well
+	err = c.Invoke(func(ex *example) {
+		as.Equal("token", ex.text)
+	})
+	as.NoError(err)
+
+	_, err = c.GetByToken("other")
+	as.EqualError(err, `token "other" was not registered`)
+}
+
+func TestRegisterTokenDecorator(t *testing.T) {
+	as := assert.New(t)
+	c := NewContainer()
+
+	err := c.Register(func() *example {
+		return newExample("repo")
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.RegisterToken("logged", func(inner *example) *example {
+		return newExample("logged " + inner.text)
+	}, Singleton, Trimmable())
+	as.Equal(errDecoratorOptions, err)
+
+	err = c.RegisterToken("logged", func(inner *example) *example {
+		return newExample("logged " + inner.text)
+	}, Singleton)
+	as.NoError(err)
+
+	err = c.RegisterToken("logged", func(inner *example) *example {
+		return inner
+	}, Singleton)
+	as.EqualError(err, `token "logged" was already registered for *di.example`)
+
+	err = c.Build()
+	as.NoError(err)
+
+	val, err := c.GetByToken("logged")
+	as.NoError(err)
+	as.Equal("logged repo", val.(*example).text)
+}
@@ -0,0 +1,43 @@
+package di
+
+import "fmt"
+
+// Verify checks that providers form a valid wiring without calling them: they are registered in a new container
+// that is built and discarded, so that missing and cyclic dependencies are reported, as well as singletons that depend
+// on Scoped dependencies, directly or through Transient ones, which would outlive the request. Each provider may be followed by its Lifetime,
+// providers without one are registered as Transient:
+//  err := di.Verify(NewDB, di.Singleton, NewRepository, di.Scoped, NewHandler)
+func Verify(providers ...interface{}) error {
+	c := NewContainer(WithLazySingletons())
+	for i := 0; i < len(providers); i++ {
+		provider, lifetime := providers[i], Transient
+		if i+1 < len(providers) {
+			if l, ok := providers[i+1].(Lifetime); ok {
+				lifetime = l
+				i++
+			}
+		}
+
+		if err := c.Register(provider, lifetime); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Build(); err != nil {
+		return err
+	}
+
+	for _, t := range c.registered {
+		if c.lifetimes[t] != Singleton {
+			continue
+		}
+
+		for _, dep := range c.heldDependencies(t) {
+			if c.lifetimes[dep] == Scoped {
+				return fmt.Errorf("singleton %s depends on scoped %s", typeName(t), typeName(dep))
+			}
+		}
+	}
+
+	return nil
+}
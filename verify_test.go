@@ -0,0 +1,50 @@
+package di
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	as := assert.New(t)
+
+	called := false
+	newExample := func() *example {
+		called = true
+		return &example{}
+	}
+	newExample2 := func(ex *example) *example2 {
+		return newExample2(ex)
+	}
+	newExample3 := func(ex2 *example2) *example3 {
+		return newExample3()
+	}
+
+	err := Verify(newExample, Singleton, newExample2, newExample3, Scoped)
+	as.NoError(err)
+	// providers are not called
+	as.False(called)
+
+	err = Verify(newExample2, newExample3)
+	as.EqualError(err, "type *di.example was not registered")
+
+	err = Verify(newExample, Scoped, newExample2, Singleton)
+	as.EqualError(err, "singleton *di.example2 depends on scoped *di.example")
+
+	// scoped dependency is captured through a transient one
+	err = Verify(newExample, Scoped, newExample2, Transient, newExample3, Singleton)
+	as.EqualError(err, "singleton *di.example3 depends on scoped *di.example")
+
+	// singletons hold their own dependencies
+	err = Verify(newExample, Singleton, newExample2, Singleton, newExample3, Singleton)
+	as.NoError(err)
+
+	err = Verify(newExample, func(ex3 *example3) *example {
+		return nil
+	})
+	as.EqualError(err, "dependency *di.example was already registered")
+
+	err = Verify(Singleton, newExample)
+	as.Equal(errNotAFunction, err)
+}